@@ -90,6 +90,9 @@ const (
 	MaxLists        = 5
 	MaxListsPremium = 25
 
+	// Max length of the content of a list as shown in the list editor, one item per line
+	MaxListContentLength = 5000
+
 	MaxRuleParts = 25
 
 	MaxRulesets        = 10
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/jonas747/discordgo"
 	"github.com/jonas747/dstate"
@@ -13,6 +16,7 @@ import (
 	"github.com/jonas747/yagpdb/bot/eventsystem"
 	"github.com/jonas747/yagpdb/commands"
 	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/scheduledevents2"
 	"github.com/volatiletech/null"
 	"github.com/volatiletech/sqlboiler/boil"
 	"github.com/volatiletech/sqlboiler/queries/qm"
@@ -25,6 +29,11 @@ func (p *Plugin) BotInit() {
 	eventsystem.AddHandlerAsyncLast(p.handleGuildMemberUpdate, eventsystem.EventGuildMemberUpdate)
 	eventsystem.AddHandlerAsyncLast(p.handleMsgUpdate, eventsystem.EventMessageUpdate)
 	eventsystem.AddHandlerAsyncLast(p.handleGuildMemberJoin, eventsystem.EventGuildMemberAdd)
+
+	scheduledevents2.RegisterHandler("amod2_lift_lockdown", LiftLockdownData{}, p.handleLiftLockdown)
+	scheduledevents2.RegisterHandler("amod2_reset_slowmode", ResetSlowmodeData{}, p.handleResetSlowmode)
+
+	go p.runJoinTrackerCleanup()
 }
 
 func (p *Plugin) handleMsgUpdate(evt *eventsystem.EventData) {
@@ -88,7 +97,7 @@ func (p *Plugin) checkViolationTriggers(ctxData *TriggeredRuleData, violationNam
 	}
 
 	// retrieve users violations
-	userViolations, err := models.AutomodViolations(qm.Where("guild_id = ? AND user_id = ? AND name = ?", ctxData.GS.ID, ctxData.MS.ID, violationName)).AllG(context.Background())
+	userViolations, err := models.AutomodViolations(qm.Where("guild_id = ? AND user_id = ? AND name = ? AND (decays_at IS NULL OR decays_at > now())", ctxData.GS.ID, ctxData.MS.ID, violationName)).AllG(context.Background())
 	if err != nil {
 		logger.WithError(err).Error("automod failed retrieving user violations")
 		return
@@ -206,6 +215,12 @@ func (p *Plugin) handleGuildMemberJoin(evt *eventsystem.EventData) {
 	gs := bot.State.Guild(true, evtData.GuildID)
 	ms := dstate.MSFromDGoMember(gs, evtData.Member)
 
+	// only track joins on servers using the join raid trigger
+	rulesets, err := p.FetchGuildRulesets(gs)
+	if err == nil && hasJoinRaidRule(rulesets) {
+		recentJoins.add(evtData.GuildID, ms.ID, time.Now())
+	}
+
 	p.checkJoin(ms)
 	p.checkUsername(ms)
 }
@@ -280,37 +295,7 @@ func (p *Plugin) CheckTriggers(rulesets []*ParsedRuleset, ms *dstate.MemberState
 		}
 
 		// Check for triggered rules in this ruleset
-		var triggeredRules []*ParsedRule
-		var activatedTriggers []*ParsedPart
-
-	OUTER:
-		for _, rule := range rs.Rules {
-
-			// Check the rule conditions
-			ctxData.CurrentRule = rule
-			if !p.CheckConditions(ctxData, rule.Conditions) {
-				continue OUTER
-			}
-			ctxData.CurrentRule = nil
-
-			for _, trig := range rule.Triggers {
-
-				activated, err := checkF(trig)
-				if err != nil {
-					logger.WithError(err).WithField("part_id", trig.RuleModel.ID).Error("failed checking trigger")
-					continue
-				}
-
-				if activated {
-					triggeredRules = append(triggeredRules, rule)
-					activatedTriggers = append(activatedTriggers, trig)
-					break
-				}
-
-			}
-
-		}
-
+		triggeredRules, activatedTriggers := p.checkRulesetTriggers(ctxData, rs, checkF)
 		if len(triggeredRules) < 1 {
 			// no matches :(
 			continue
@@ -324,7 +309,10 @@ func (p *Plugin) CheckTriggers(rulesets []*ParsedRuleset, ms *dstate.MemberState
 		}
 
 		go p.RulesetRulesTriggered(ctxData, true)
-		activatededRules = true
+		if !rs.RSModel.Simulate {
+			// simulated rulesets shouldn't interfere with anything else, such as commands
+			activatededRules = true
+		}
 
 		logger.WithField("guild", ctxData.GS.ID).Info("automod triggered ", len(triggeredRules), " rules")
 	}
@@ -332,6 +320,40 @@ func (p *Plugin) CheckTriggers(rulesets []*ParsedRuleset, ms *dstate.MemberState
 	return activatededRules
 }
 
+// checkRulesetTriggers returns the rules in the ruleset that had their conditions met and one of their triggers activated,
+// along with the trigger that activated for each of them
+func (p *Plugin) checkRulesetTriggers(ctxData *TriggeredRuleData, rs *ParsedRuleset, checkF func(trp *ParsedPart) (activated bool, err error)) (triggeredRules []*ParsedRule, activatedTriggers []*ParsedPart) {
+OUTER:
+	for _, rule := range rs.Rules {
+
+		// Check the rule conditions
+		ctxData.CurrentRule = rule
+		if !p.CheckConditions(ctxData, rule.Conditions) {
+			continue OUTER
+		}
+		ctxData.CurrentRule = nil
+
+		for _, trig := range rule.Triggers {
+
+			activated, err := checkF(trig)
+			if err != nil {
+				logger.WithError(err).WithField("part_id", trig.RuleModel.ID).Error("failed checking trigger")
+				continue
+			}
+
+			if activated {
+				triggeredRules = append(triggeredRules, rule)
+				activatedTriggers = append(activatedTriggers, trig)
+				break
+			}
+
+		}
+
+	}
+
+	return
+}
+
 func (p *Plugin) RulesetRulesTriggered(ctxData *TriggeredRuleData, checkedConditions bool) {
 	ruleset := ctxData.Ruleset
 
@@ -387,17 +409,43 @@ func (p *Plugin) RulesetRulesTriggeredCondsPassed(ruleset *ParsedRuleset, trigge
 
 	loggedModels := make([]*models.AutomodTriggeredRule, len(triggeredRules))
 
+	// simulated rulesets only log what would have happened without applying any effects
+	simulate := ruleset.RSModel.Simulate
+
 	// apply the effects
 	for i, rule := range triggeredRules {
 		ctxData.CurrentRule = rule
 
+		tID := int64(0)
+		tTypeID := 0
+		for _, v := range ctxData.ActivatedTriggers {
+			if v.RuleModel.RuleID == rule.Model.ID {
+				tID = v.RuleModel.ID
+				tTypeID = v.RuleModel.TypeID
+				break
+			}
+		}
+
+		effectTypeIDs := make([]int64, 0, len(rule.Effects))
 		for _, effect := range rule.Effects {
-			go func(fx *ParsedPart, ctx *TriggeredRuleData) {
+			effectTypeIDs = append(effectTypeIDs, int64(effect.RuleModel.TypeID))
+			if simulate {
+				continue
+			}
+
+			go func(fx *ParsedPart, ctx *TriggeredRuleData, ruleID int64, triggerTypeID int) {
 				err := fx.Part.(Effect).Apply(ctx, fx.ParsedSettings)
 				if err != nil {
 					logger.WithError(err).WithField("guild", ruleset.RSModel.GuildID).WithField("part", fx.Part.Name()).Error("failed applying automod effect")
+					return
 				}
-			}(effect, ctxData.Clone())
+
+				// only count the effects that actually succeeded
+				err = incrRuleStats(common.PQ, ruleset.RSModel.GuildID, ruleID, triggerTypeID, time.Now(), 0, 1, 0)
+				if err != nil {
+					logger.WithError(err).WithField("guild", ruleset.RSModel.GuildID).Error("failed recording automod effect stats")
+				}
+			}(effect, ctxData.Clone(), rule.Model.ID, tTypeID)
 		}
 
 		// Log the rule activation
@@ -411,16 +459,6 @@ func (p *Plugin) RulesetRulesTriggeredCondsPassed(ruleset *ParsedRuleset, trigge
 			cid = ctxData.CS.ID
 		}
 
-		tID := int64(0)
-		tTypeID := 0
-		for _, v := range ctxData.ActivatedTriggers {
-			if v.RuleModel.RuleID == rule.Model.ID {
-				tID = v.RuleModel.ID
-				tTypeID = v.RuleModel.TypeID
-				break
-			}
-		}
-
 		serializedExtraData := []byte("{}")
 		if ctxData.Message != nil {
 			var err error
@@ -443,6 +481,8 @@ func (p *Plugin) RulesetRulesTriggeredCondsPassed(ruleset *ParsedRuleset, trigge
 			UserID:        ctxData.MS.ID,
 			UserName:      ctxData.MS.Username + "#" + ctxData.MS.StrDiscriminator(),
 			Extradata:     serializedExtraData,
+			Simulated:     simulate,
+			EffectTypeids: effectTypeIDs,
 		}
 	}
 
@@ -465,6 +505,53 @@ func (p *Plugin) RulesetRulesTriggeredCondsPassed(ruleset *ParsedRuleset, trigge
 	if err != nil {
 		logger.WithError(err).Error("failed committing logging transaction")
 	}
+
+	// stats are recorded separately so a failure doesn't lose the log entries
+	err = recordTriggeredRuleStats(common.PQ, loggedModels)
+	if err != nil {
+		logger.WithError(err).WithField("guild", ctxData.GS.ID).Error("failed recording automod rule stats")
+	}
+
+	if simulate && ruleset.RSModel.SimulateLogChannel != 0 {
+		p.sendSimulationLog(ctxData.GS, ruleset.RSModel.SimulateLogChannel, loggedModels)
+	}
+}
+
+// sendSimulationLog posts a summary of what a simulated ruleset would have done to the simulation log channel
+func (p *Plugin) sendSimulationLog(gs *dstate.GuildState, channelID int64, entries []*models.AutomodTriggeredRule) {
+	var out strings.Builder
+	for _, v := range entries {
+		effects := make([]string, 0, len(v.EffectTypeids))
+		for _, e := range v.EffectTypeids {
+			if part, ok := RulePartMap[int(e)]; ok {
+				effects = append(effects, "`"+part.Name()+"`")
+			}
+		}
+
+		effectsStr := "nothing"
+		if len(effects) > 0 {
+			effectsStr = strings.Join(effects, ", ")
+		}
+
+		triggerName := "unknown"
+		if part, ok := RulePartMap[v.TriggerTypeid]; ok {
+			triggerName = part.Name()
+		}
+
+		channelStr := ""
+		if v.ChannelID != 0 {
+			channelStr = fmt.Sprintf(" in <#%d>", v.ChannelID)
+		}
+
+		out.WriteString(fmt.Sprintf("**[Simulated]** RS:`%s` R:`%s` T:`%s` - user `%s` (%d)%s, would have applied: %s\n",
+			v.RulesetName, v.RuleName, triggerName, v.UserName, v.UserID, channelStr, effectsStr))
+	}
+
+	msg := common.CutStringShort(common.EscapeSpecialMentions(out.String()), 2000)
+	_, _, err := bot.SendMessageGS(gs, channelID, msg)
+	if err != nil {
+		logger.WithError(err).WithField("guild", gs.ID).Error("failed sending automod simulation log")
+	}
 }
 
 const (
@@ -501,27 +588,41 @@ func (p *Plugin) FetchGuildRulesets(gs *dstate.GuildState) ([]*ParsedRuleset, er
 	return cast, nil
 }
 
-func FetchGuildLists(gs *dstate.GuildState) ([]*models.AutomodList, error) {
+// CachedList is a list with its content also normalized ahead of time, for the triggers normalizing lookalike characters
+type CachedList struct {
+	*models.AutomodList
+	NormalizedContent []string
+}
+
+func FetchGuildLists(gs *dstate.GuildState) ([]*CachedList, error) {
 	v, err := gs.UserCacheFetch(true, CacheKeyLists, func() (interface{}, error) {
 		lists, err := models.AutomodLists(qm.Where("guild_id = ?", gs.ID)).AllG(context.Background())
 		if err != nil {
 			return nil, err
 		}
 
-		return []*models.AutomodList(lists), nil
+		result := make([]*CachedList, len(lists))
+		for i, v := range lists {
+			result[i] = &CachedList{
+				AutomodList:       v,
+				NormalizedContent: normalizeWordList(v.Content),
+			}
+		}
+
+		return result, nil
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	cast := v.([]*models.AutomodList)
+	cast := v.([]*CachedList)
 	return cast, nil
 }
 
 var ErrListNotFound = errors.New("list not found")
 
-func FindFetchGuildList(gs *dstate.GuildState, listID int64) (*models.AutomodList, error) {
+func FindFetchGuildList(gs *dstate.GuildState, listID int64) (*CachedList, error) {
 	lists, err := FetchGuildLists(gs)
 	if err != nil {
 		return nil, err
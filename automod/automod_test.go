@@ -3,6 +3,10 @@ package automod
 import (
 	"strconv"
 	"testing"
+	"time"
+
+	"github.com/jonas747/yagpdb/automod/models"
+	"github.com/volatiletech/null"
 )
 
 func TestPrepareMessageForWordCheck(t *testing.T) {
@@ -25,3 +29,107 @@ func TestPrepareMessageForWordCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeDuplicateContent(t *testing.T) {
+	cases := []struct {
+		input  string
+		output string
+	}{
+		{input: "Hello World", output: "hello world"},
+		{input: "**hello**   world!", output: "hello world"},
+		{input: "__HeLLo__ ~~world~~", output: "hello world"},
+		{input: " 🎉🎉 ", output: "🎉🎉"},
+		{input: "", output: ""},
+	}
+
+	for i, c := range cases {
+		t.Run("#"+strconv.Itoa(i), func(st *testing.T) {
+			result := normalizeDuplicateContent(c.input)
+			if result != c.output {
+				st.Errorf("got: %q, expected: %q", result, c.output)
+			}
+		})
+	}
+}
+
+func TestCountEmojis(t *testing.T) {
+	cases := []struct {
+		input      string
+		shortcodes bool
+		emojis     int
+		other      int
+	}{
+		{input: "hi 😀😀", emojis: 2, other: 2},
+		{input: "👍🏽 🇳🇴 👨‍👩‍👧", emojis: 3, other: 0},
+		{input: "❤️❤️❤️", emojis: 3, other: 0},
+		{input: "1️⃣ <:pog:1234> <a:x:55>", emojis: 3, other: 0},
+		{input: "wew :smile:", shortcodes: false, emojis: 0, other: 10},
+		{input: "wew :smile:", shortcodes: true, emojis: 1, other: 3},
+	}
+
+	for i, c := range cases {
+		t.Run("#"+strconv.Itoa(i), func(st *testing.T) {
+			emojis, other := countEmojis(c.input, c.shortcodes)
+			if emojis != c.emojis || other != c.other {
+				st.Errorf("got: %d emojis and %d other, expected: %d emojis and %d other", emojis, other, c.emojis, c.other)
+			}
+		})
+	}
+}
+
+func TestNormalizeConfusables(t *testing.T) {
+	cases := []struct {
+		input  string
+		output string
+	}{
+		{input: "ѕhit", output: "shit"},
+		{input: "ｆｕｃｋ", output: "fuck"},
+		{input: "b​ad", output: "bad"},
+		{input: "z̴̢̛a̸l̵g̶o̷", output: "zalgo"},
+		{input: "𝐛𝐚𝐝 𝟏", output: "bad 1"},
+		{input: "ʙᴀᴅ", output: "bad"},
+		{input: "normal", output: "normal"},
+	}
+
+	for i, c := range cases {
+		t.Run("#"+strconv.Itoa(i), func(st *testing.T) {
+			result := NormalizeConfusables(c.input)
+			if result != c.output {
+				st.Errorf("got: %q, expected: %q", result, c.output)
+			}
+		})
+	}
+}
+
+func TestViolationWeightAt(t *testing.T) {
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		weight   int
+		decaysAt null.Time
+		elapsed  time.Duration
+		expected int
+	}{
+		{weight: 3, elapsed: time.Hour * 1000, expected: 3},
+		{weight: 3, decaysAt: null.TimeFrom(created.Add(time.Hour * 3)), elapsed: 0, expected: 3},
+		{weight: 3, decaysAt: null.TimeFrom(created.Add(time.Hour * 3)), elapsed: time.Minute * 59, expected: 3},
+		{weight: 3, decaysAt: null.TimeFrom(created.Add(time.Hour * 3)), elapsed: time.Hour, expected: 2},
+		{weight: 3, decaysAt: null.TimeFrom(created.Add(time.Hour * 3)), elapsed: time.Hour * 2, expected: 1},
+		{weight: 3, decaysAt: null.TimeFrom(created.Add(time.Hour * 3)), elapsed: time.Hour * 3, expected: 0},
+		{weight: 1, decaysAt: null.TimeFrom(created.Add(time.Hour)), elapsed: time.Hour * 5, expected: 0},
+	}
+
+	for i, c := range cases {
+		t.Run("#"+strconv.Itoa(i), func(st *testing.T) {
+			v := &models.AutomodViolation{
+				CreatedAt: created,
+				Weight:    c.weight,
+				DecaysAt:  c.decaysAt,
+			}
+
+			result := violationWeightAt(v, created.Add(c.elapsed))
+			if result != c.expected {
+				st.Errorf("got: %d, expected: %d", result, c.expected)
+			}
+		})
+	}
+}
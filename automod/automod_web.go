@@ -2,6 +2,7 @@ package automod
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -10,10 +11,12 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fatih/structs"
 	"github.com/gorilla/schema"
 	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate"
 	"github.com/jonas747/yagpdb/automod/models"
 	"github.com/jonas747/yagpdb/bot"
 	"github.com/jonas747/yagpdb/common"
@@ -50,9 +53,17 @@ func (p *Plugin) InitWeb() {
 
 	muxer.Handle(pat.Get("/"), getIndexHandler)
 	muxer.Handle(pat.Get(""), getIndexHandler)
-	muxer.Handle(pat.Get("/logs"), web.ControllerHandler(p.handleGetLogs, "automod_index"))
+	getLogsHandler := web.ControllerHandler(p.handleGetLogs, "automod_index")
+	muxer.Handle(pat.Get("/logs"), getLogsHandler)
+	muxer.Handle(pat.Post("/logs/:entryID/false_positive"), web.ControllerPostHandler(p.handlePostLogFalsePositive, getLogsHandler, nil, "Marked a automod log entry as a false positive"))
+
+	muxer.Handle(pat.Get("/stats"), web.ControllerHandler(p.handleGetStats, "automod_index"))
+	muxer.Handle(pat.Get("/stats/charts"), web.APIHandler(p.handleGetStatsCharts))
+
+	muxer.Handle(pat.Post("/test"), web.APIHandler(p.handlePostAutomodTest))
 
 	muxer.Handle(pat.Post("/new_ruleset"), web.ControllerPostHandler(p.handlePostAutomodCreateRuleset, getIndexHandler, CreateRulesetData{}, "Created a new automod ruleset"))
+	muxer.Handle(pat.Post("/import_ruleset"), web.ControllerPostHandler(p.handlePostAutomodImportRuleset, getIndexHandler, ImportRulesetData{}, "Imported a automod ruleset"))
 
 	// List handlers
 	muxer.Handle(pat.Post("/new_list"), web.ControllerPostHandler(p.handlePostAutomodCreateList, getIndexHandler, CreateListData{}, "Created a new automod list"))
@@ -72,6 +83,7 @@ func (p *Plugin) InitWeb() {
 
 	rulesetMuxer.Handle(pat.Post("/update"), web.ControllerPostHandler(p.handlePostAutomodUpdateRuleset, getRulesetHandler, UpdateRulesetData{}, "Updated a ruleset"))
 	rulesetMuxer.Handle(pat.Post("/delete"), web.ControllerPostHandler(p.handlePostAutomodDeleteRuleset, getIndexHandler, nil, "Deleted a ruleset"))
+	rulesetMuxer.Handle(pat.Get("/export"), web.APIHandler(p.handleGetAutomodExportRuleset))
 
 	rulesetMuxer.Handle(pat.Post("/new_rule"), web.ControllerPostHandler(p.handlePostAutomodCreateRule, getRulesetHandler, CreateRuleData{}, "Created a new automod rule"))
 	rulesetMuxer.Handle(pat.Post("/rule/:ruleID/delete"), web.ControllerPostHandler(p.handlePostAutomodDeleteRule, getRulesetHandler, nil, "Deleted a automod rule"))
@@ -132,6 +144,129 @@ func (p *Plugin) handleGetLogs(w http.ResponseWriter, r *http.Request) (web.Temp
 	return p.handleGetAutomodIndex(w, r)
 }
 
+// handlePostLogFalsePositive marks a log entry as a false positive, which is counted in the rule stats
+func (p *Plugin) handlePostLogFalsePositive(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	g, tmpl := web.GetBaseCPContextData(r.Context())
+
+	id, _ := strconv.ParseInt(pat.Param(r, "entryID"), 10, 64)
+	entry, err := models.AutomodTriggeredRules(qm.Where("guild_id = ? AND id = ?", g.ID, id)).OneG(r.Context())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return tmpl.AddAlerts(web.ErrorAlert("Unknown log entry")), nil
+		}
+		return tmpl, err
+	}
+
+	if entry.FalsePositive {
+		return tmpl.AddAlerts(web.WarningAlert("That log entry is already marked as a false positive")), nil
+	}
+
+	tx, err := common.PQ.BeginTx(r.Context(), nil)
+	if err != nil {
+		return tmpl, err
+	}
+
+	entry.FalsePositive = true
+	_, err = entry.Update(r.Context(), tx, boil.Whitelist("false_positive"))
+	if err != nil {
+		tx.Rollback()
+		return tmpl, err
+	}
+
+	// stats are kept in the hour the rule triggered, older entries have already been cleaned up
+	if entry.RuleID.Valid && time.Since(entry.CreatedAt) < RuleStatsRetention {
+		err = incrRuleStats(tx, g.ID, entry.RuleID.Int64, entry.TriggerTypeid, entry.CreatedAt, 0, 0, 1)
+		if err != nil {
+			tx.Rollback()
+			return tmpl, err
+		}
+	}
+
+	return tmpl, tx.Commit()
+}
+
+func statsDaysFromQuery(r *http.Request) int {
+	days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+	if days < 1 {
+		days = 7
+	} else if days > MaxRuleStatsDays {
+		days = MaxRuleStatsDays
+	}
+
+	return days
+}
+
+func (p *Plugin) handleGetStats(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	g, tmpl := web.GetBaseCPContextData(r.Context())
+
+	days := statsDaysFromQuery(r)
+	totals, _, err := RetrieveRuleStats(r.Context(), g.ID, days)
+	if err != nil {
+		return tmpl, err
+	}
+
+	tmpl["InStats"] = true
+	tmpl["AutomodStatsDays"] = days
+	tmpl["AutomodRuleStats"] = totals
+
+	return p.handleGetAutomodIndex(w, r)
+}
+
+func (p *Plugin) handleGetStatsCharts(w http.ResponseWriter, r *http.Request) interface{} {
+	g, _ := web.GetBaseCPContextData(r.Context())
+
+	days := statsDaysFromQuery(r)
+	totals, hourly, err := RetrieveRuleStats(r.Context(), g.ID, days)
+	if err != nil {
+		return err
+	}
+
+	return map[string]interface{}{
+		"days":   days,
+		"rules":  totals,
+		"hourly": hourly,
+	}
+}
+
+// handlePostAutomodTest runs a fake message from the json body through all the rulesets on the server without applying any effects
+func (p *Plugin) handlePostAutomodTest(w http.ResponseWriter, r *http.Request) interface{} {
+	g, _ := web.GetBaseCPContextData(r.Context())
+
+	var input RuleTestInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		return web.NewPublicError("Invalid request body")
+	}
+
+	if input.Content == "" {
+		return web.NewPublicError("No message content provided")
+	}
+
+	rulesets, err := models.AutomodRulesets(qm.Where("guild_id=?", g.ID), qm.OrderBy("id asc"),
+		qm.Load("RulesetAutomodRules.RuleAutomodRuleData"), qm.Load("RulesetAutomodRulesetConditions")).AllG(r.Context())
+	if err != nil {
+		return err
+	}
+
+	parsedSets := make([]*ParsedRuleset, 0, len(rulesets))
+	for _, v := range rulesets {
+		parsed, err := ParseRuleset(v)
+		if err != nil {
+			return err
+		}
+		parsedSets = append(parsedSets, parsed)
+	}
+
+	gs := dstate.NewGuildState(g, nil)
+	cs := gs.Channel(true, input.ChannelID)
+	if cs == nil {
+		return web.NewPublicError("Unknown channel")
+	}
+
+	ms := NewTestMember(gs, &input)
+	return p.TestMessage(parsedSets, ms, cs, input.Content, time.Now().Add(-time.Duration(input.AccountAge)*time.Minute))
+}
+
 type CreateRulesetData struct {
 	Name string `valid:",1,100"`
 }
@@ -161,6 +296,219 @@ func (p *Plugin) handlePostAutomodCreateRuleset(w http.ResponseWriter, r *http.R
 	return tmpl, err
 }
 
+type ImportRulesetData struct {
+	Data string `valid:",1,100000"`
+}
+
+func (p *Plugin) handlePostAutomodImportRuleset(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	g, tmpl := web.GetBaseCPContextData(r.Context())
+	data := r.Context().Value(common.ContextKeyParsedForm).(*ImportRulesetData)
+
+	export, err := ParseRulesetExport([]byte(data.Data))
+	if err != nil {
+		tmpl.AddAlerts(web.ErrorAlert("Invalid ruleset export: ", err.Error()))
+		return tmpl, nil
+	}
+
+	// Check the ruleset and rule limits
+	currentRulesets, err := models.AutomodRulesets(qm.Where("guild_id=?", g.ID)).CountG(r.Context())
+	if err != nil {
+		return tmpl, err
+	}
+	if currentRulesets >= int64(GuildMaxRulesets(g.ID)) {
+		tmpl.AddAlerts(web.ErrorAlert("Reached max number of rulesets, ", MaxRulesets))
+		return tmpl, nil
+	}
+
+	totalRules, err := models.AutomodRules(qm.Where("guild_id = ? ", g.ID)).CountG(r.Context())
+	if err != nil {
+		return tmpl, err
+	}
+	if totalRules+int64(len(export.Rules)) > int64(GuildMaxTotalRules(g.ID)) {
+		tmpl.AddAlerts(web.ErrorAlert(fmt.Sprintf("Importing this ruleset would exceed the max number of rules, %d for normal servers and %d for premium servers", MaxTotalRules, MaxTotalRulesPremium)))
+		return tmpl, nil
+	}
+
+	// Reuse existing lists with the same name and kind, otherwise create new ones
+	currentLists, err := models.AutomodLists(qm.Where("guild_id = ?", g.ID)).AllG(r.Context())
+	if err != nil {
+		return tmpl, err
+	}
+
+	listMapping := make(map[int64]int64)
+	newLists := make([]*models.AutomodList, 0)
+	newListsOldIDs := make([]int64, 0)
+OUTER:
+	for _, v := range export.Lists {
+		for _, existing := range currentLists {
+			if existing.Name == v.Name && existing.Kind == v.Kind {
+				listMapping[v.ID] = existing.ID
+				continue OUTER
+			}
+		}
+
+		content := v.Content
+		if content == nil {
+			content = []string{}
+		}
+
+		newLists = append(newLists, &models.AutomodList{
+			GuildID: g.ID,
+			Name:    v.Name,
+			Kind:    v.Kind,
+			Content: content,
+		})
+		newListsOldIDs = append(newListsOldIDs, v.ID)
+	}
+
+	if len(currentLists)+len(newLists) > GuildMaxLists(g.ID) {
+		tmpl.AddAlerts(web.ErrorAlert(fmt.Sprintf("Importing this ruleset would exceed the max number of lists, %d for normal servers and %d for premium servers", MaxLists, MaxListsPremium)))
+		return tmpl, nil
+	}
+
+	tx, err := common.PQ.BeginTx(r.Context(), nil)
+	if err != nil {
+		return tmpl, err
+	}
+
+	for i, v := range newLists {
+		err = v.Insert(r.Context(), tx, boil.Infer())
+		if err != nil {
+			tx.Rollback()
+			return tmpl, err
+		}
+
+		listMapping[newListsOldIDs[i]] = v.ID
+	}
+
+	// unknown channels are reset to none instead of failing the validation
+	simulateLog := &struct {
+		Channel int64 `valid:"channel,true"`
+	}{Channel: export.SimulateLogChannel}
+	web.ValidateForm(g, tmpl, simulateLog)
+
+	ruleset := &models.AutomodRuleset{
+		GuildID:            g.ID,
+		Name:               export.Name,
+		Enabled:            export.Enabled,
+		Simulate:           export.Simulate,
+		SimulateLogChannel: simulateLog.Channel,
+	}
+	err = ruleset.Insert(r.Context(), tx, boil.Infer())
+	if err != nil {
+		tx.Rollback()
+		return tmpl, err
+	}
+
+	conditions, ok, err := importRuleParts(g, tmpl, export.Conditions, listMapping)
+	if !ok || err != nil {
+		tx.Rollback()
+		return tmpl, err
+	}
+
+	for _, v := range conditions {
+		cond := &models.AutomodRulesetCondition{
+			GuildID:   g.ID,
+			RulesetID: ruleset.ID,
+			Kind:      v.Kind,
+			TypeID:    v.TypeID,
+			Settings:  v.Settings,
+		}
+
+		err = cond.Insert(r.Context(), tx, boil.Infer())
+		if err != nil {
+			tx.Rollback()
+			return tmpl, err
+		}
+	}
+
+	for _, exportedRule := range export.Rules {
+		rule := &models.AutomodRule{
+			GuildID:   g.ID,
+			RulesetID: ruleset.ID,
+			Name:      exportedRule.Name,
+		}
+
+		err = rule.Insert(r.Context(), tx, boil.Infer())
+		if err != nil {
+			tx.Rollback()
+			return tmpl, err
+		}
+
+		var parts []*models.AutomodRuleDatum
+		for _, section := range [][]*ExportedPart{exportedRule.Triggers, exportedRule.Conditions, exportedRule.Effects} {
+			sectionParts, ok, err := importRuleParts(g, tmpl, section, listMapping)
+			if !ok || err != nil {
+				tx.Rollback()
+				return tmpl, err
+			}
+			parts = append(parts, sectionParts...)
+		}
+
+		parts, ok, err = CheckLimits(tx, rule, tmpl, parts)
+		if !ok || err != nil {
+			tx.Rollback()
+			return tmpl, err
+		}
+
+		for _, part := range parts {
+			part.RuleID = rule.ID
+			err = part.Insert(r.Context(), tx, boil.Infer())
+			if err != nil {
+				tx.Rollback()
+				return tmpl, err
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return tmpl, err
+	}
+
+	bot.EvictGSCache(g.ID, CacheKeyRulesets)
+	bot.EvictGSCache(g.ID, CacheKeyLists)
+
+	return tmpl, nil
+}
+
+// importRuleParts turns exported parts into db models, remapping the list references and
+// validating the settings against the guild (roles, channels and so on)
+func importRuleParts(g *discordgo.Guild, tmpl web.TemplateData, parts []*ExportedPart, listMapping map[int64]int64) (result []*models.AutomodRuleDatum, validationOK bool, err error) {
+	for _, v := range parts {
+		settings, err := RemapPartListIDs(v, listMapping)
+		if err != nil {
+			return nil, false, err
+		}
+
+		part := RulePartMap[v.TypeID]
+		if dst := part.DataType(); dst != nil {
+			err = json.Unmarshal(settings, dst)
+			if err != nil {
+				return nil, false, err
+			}
+
+			if !web.ValidateForm(g, tmpl, dst) {
+				return nil, false, nil
+			}
+
+			settings, err = json.Marshal(dst)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		result = append(result, &models.AutomodRuleDatum{
+			GuildID:  g.ID,
+			Kind:     int(part.Kind()),
+			TypeID:   v.TypeID,
+			Settings: settings,
+		})
+	}
+
+	return result, true, nil
+}
+
 type CreateListData struct {
 	Name string `valid:",1,50"`
 }
@@ -193,6 +541,7 @@ func (p *Plugin) handlePostAutomodCreateList(w http.ResponseWriter, r *http.Requ
 }
 
 type UpdateListData struct {
+	// keep in sync with MaxListContentLength
 	Content string `valid:",0,5000"`
 }
 
@@ -303,9 +652,11 @@ func (p *Plugin) handlePostAutomodCreateRule(w http.ResponseWriter, r *http.Requ
 }
 
 type UpdateRulesetData struct {
-	Name       string `valid:",1,50"`
-	Enabled    bool
-	Conditions []RuleRowData
+	Name               string `valid:",1,50"`
+	Enabled            bool
+	Simulate           bool
+	SimulateLogChannel int64 `valid:"channel,true"`
+	Conditions         []RuleRowData
 }
 
 func (p *Plugin) handlePostAutomodUpdateRuleset(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
@@ -356,7 +707,9 @@ func (p *Plugin) handlePostAutomodUpdateRuleset(w http.ResponseWriter, r *http.R
 	// Update the ruleset model itself
 	ruleset.Name = data.Name
 	ruleset.Enabled = data.Enabled
-	_, err = ruleset.Update(r.Context(), tx, boil.Whitelist("name", "enabled"))
+	ruleset.Simulate = data.Simulate
+	ruleset.SimulateLogChannel = data.SimulateLogChannel
+	_, err = ruleset.Update(r.Context(), tx, boil.Whitelist("name", "enabled", "simulate", "simulate_log_channel"))
 	if err != nil {
 		tx.Rollback()
 		return tmpl, err
@@ -392,6 +745,20 @@ func (p *Plugin) handlePostAutomodDeleteRuleset(w http.ResponseWriter, r *http.R
 	return tmpl, err
 }
 
+func (p *Plugin) handleGetAutomodExportRuleset(w http.ResponseWriter, r *http.Request) interface{} {
+	g, _ := web.GetBaseCPContextData(r.Context())
+
+	ruleset := r.Context().Value(CtxKeyCurrentRuleset).(*models.AutomodRuleset)
+
+	lists, err := models.AutomodLists(qm.Where("guild_id = ?", g.ID)).AllG(r.Context())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"automod_ruleset_%d.json\"", ruleset.ID))
+	return ExportRuleset(ruleset, lists)
+}
+
 type UpdateRuleData struct {
 	Name       string `valid:",1,50"`
 	Triggers   []RuleRowData
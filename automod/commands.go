@@ -3,9 +3,11 @@ package automod
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jonas747/dcmd"
 	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate"
 	"github.com/jonas747/yagpdb/automod/models"
 	"github.com/jonas747/yagpdb/commands"
 	"github.com/volatiletech/sqlboiler/boil"
@@ -49,6 +51,39 @@ func (p *Plugin) AddCommands() {
 		},
 	}
 
+	cmdSimulateRuleset := &commands.YAGCommand{
+		Name:         "Simulate",
+		Aliases:      []string{"sim"},
+		CmdCategory:  commands.CategoryModeration,
+		RequiredArgs: 1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "ruleset name", Type: dcmd.String},
+		},
+		Description:         "Toggles simulation mode on a ruleset, in simulation mode rules are only logged and no effects are applied",
+		RequireDiscordPerms: []int64{discordgo.PermissionManageServer, discordgo.PermissionAdministrator, discordgo.PermissionBanMembers},
+		RunFunc: func(data *dcmd.Data) (interface{}, error) {
+			rulesetName := data.Args[0].Str()
+			ruleset, err := models.AutomodRulesets(qm.Where("guild_id = ? AND name ILIKE ?", data.GS.ID, rulesetName)).OneG(data.Context())
+			if err != nil {
+				return "Unable to fine the ruleset, did you type the name correctly?", err
+			}
+
+			ruleset.Simulate = !ruleset.Simulate
+			_, err = ruleset.UpdateG(data.Context(), boil.Whitelist("simulate"))
+			if err != nil {
+				return nil, err
+			}
+
+			data.GS.UserCacheDel(true, CacheKeyRulesets)
+
+			if ruleset.Simulate {
+				return fmt.Sprintf("Ruleset **%s** is now in simulation mode, effects will only be logged", ruleset.Name), nil
+			}
+
+			return fmt.Sprintf("Ruleset **%s** is no longer in simulation mode, effects will be applied", ruleset.Name), nil
+		},
+	}
+
 	cmdViewRulesets := &commands.YAGCommand{
 		Name:                "Rulesets",
 		Aliases:             []string{"r", "list", "l"},
@@ -71,6 +106,8 @@ func (p *Plugin) AddCommands() {
 				onOff := "Enabled"
 				if !v.Enabled {
 					onOff = "Disabled"
+				} else if v.Simulate {
+					onOff = "Simulating"
 				}
 
 				out.WriteString(fmt.Sprintf("%s: %s\n", v.Name, onOff))
@@ -122,18 +159,64 @@ func (p *Plugin) AddCommands() {
 			out.WriteString(fmt.Sprintf("Last 15%s triggered automod v2 rules (UTC):\n```\n", offsetStr))
 			for _, v := range entries {
 				t := v.CreatedAt.UTC().Format("02 Jan 2006 15:04")
-				out.WriteString(fmt.Sprintf("%-17s - %s - RS:%s - R:%s - T:%s\n", t, v.UserName, v.RulesetName, v.RuleName, RulePartMap[v.TriggerTypeid].Name()))
+				simulated := ""
+				if v.Simulated {
+					simulated = " (SIM)"
+				}
+				out.WriteString(fmt.Sprintf("%-17s - %s - RS:%s - R:%s - T:%s%s\n", t, v.UserName, v.RulesetName, v.RuleName, RulePartMap[v.TriggerTypeid].Name(), simulated))
 			}
-			out.WriteString("``` `RS` = ruleset, `R` = rule, `T` = trigger")
+			out.WriteString("``` `RS` = ruleset, `R` = rule, `T` = trigger, `SIM` = simulated, no effects applied")
 
 			return out.String(), nil
 		},
 	}
 
+	cmdTest := &commands.YAGCommand{
+		Name:         "Test",
+		CmdCategory:  commands.CategoryModeration,
+		RequiredArgs: 1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "message", Type: dcmd.String},
+		},
+		ArgSwitches: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Switch: "user", Help: "Test as this member, defaults to you", Type: &commands.MemberArg{}},
+			&dcmd.ArgDef{Switch: "channel", Help: "Test in this channel, defaults to the current one", Type: dcmd.Channel},
+			&dcmd.ArgDef{Switch: "age", Help: "Override the account age (in minutes)", Type: &dcmd.IntArg{Min: 0, Max: 10000000}},
+		},
+		Description:         "Tests a message against all rulesets (including disabled ones) without applying any effects",
+		RequireDiscordPerms: []int64{discordgo.PermissionManageServer, discordgo.PermissionAdministrator, discordgo.PermissionBanMembers},
+		RunFunc: func(data *dcmd.Data) (interface{}, error) {
+			rulesets, err := p.FetchGuildRulesets(data.GS)
+			if err != nil {
+				return nil, err
+			}
+
+			ms := commands.ContextMS(data.Context())
+			if data.Switch("user").Value != nil {
+				ms = data.Switch("user").Value.(*dstate.MemberState)
+			}
+
+			var accountCreated time.Time
+			if data.Switch("age").Value != nil {
+				accountCreated = time.Now().Add(-time.Duration(data.Switch("age").Int()) * time.Minute)
+			}
+
+			cs := data.CS
+			if data.Switch("channel").Value != nil {
+				cs = data.Switch("channel").Value.(*dstate.ChannelState)
+			}
+
+			results := p.TestMessage(rulesets, ms, cs, data.Args[0].Str(), accountCreated)
+			return FormatRuleTestResults(results), nil
+		},
+	}
+
 	container := commands.CommandSystem.Root.Sub("automod", "amod")
 	container.NotFound = commands.CommonContainerNotFoundHandler(container, "")
 
 	container.AddCommand(cmdViewRulesets, cmdViewRulesets.GetTrigger())
 	container.AddCommand(cmdToggleRuleset, cmdToggleRuleset.GetTrigger())
+	container.AddCommand(cmdSimulateRuleset, cmdSimulateRuleset.GetTrigger())
 	container.AddCommand(cmdLogs, cmdLogs.GetTrigger())
+	container.AddCommand(cmdTest, cmdTest.GetTrigger())
 }
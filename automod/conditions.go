@@ -3,7 +3,6 @@ package automod
 import (
 	"time"
 
-	"github.com/jonas747/yagpdb/bot"
 	"github.com/jonas747/yagpdb/common"
 )
 
@@ -334,7 +333,7 @@ func (ac *AccountAgeCondition) UserSettings() []*SettingDef {
 func (ac *AccountAgeCondition) IsMet(data *TriggeredRuleData, settings interface{}) (bool, error) {
 	settingsCast := settings.(*AccountAgeConditionData)
 
-	created := bot.SnowflakeToTime(data.MS.ID)
+	created := data.AccountCreated()
 	minutes := int(time.Since(created).Minutes())
 	if minutes <= settingsCast.Treshold {
 		// account were made within threshold
@@ -107,4 +107,35 @@ CREATE TABLE IF NOT EXISTS automod_triggered_rules (
 CREATE INDEX IF NOT EXISTS automod_triggered_rules_guild_idx ON automod_triggered_rules(guild_id);
 `, `
 CREATE INDEX IF NOT EXISTS automod_triggered_rules_trigger_idx ON automod_triggered_rules(trigger_id);
+`, `
+ALTER TABLE automod_rulesets ADD COLUMN IF NOT EXISTS simulate BOOLEAN NOT NULL DEFAULT false;
+`, `
+ALTER TABLE automod_rulesets ADD COLUMN IF NOT EXISTS simulate_log_channel BIGINT NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE automod_triggered_rules ADD COLUMN IF NOT EXISTS simulated BOOLEAN NOT NULL DEFAULT false;
+`, `
+ALTER TABLE automod_triggered_rules ADD COLUMN IF NOT EXISTS effect_typeids INT[];
+`, `
+ALTER TABLE automod_violations ADD COLUMN IF NOT EXISTS weight INT NOT NULL DEFAULT 1;
+`, `
+ALTER TABLE automod_violations ADD COLUMN IF NOT EXISTS decays_at TIMESTAMP WITH TIME ZONE;
+`, `
+ALTER TABLE automod_triggered_rules ADD COLUMN IF NOT EXISTS false_positive BOOLEAN NOT NULL DEFAULT false;
+`, `
+CREATE TABLE IF NOT EXISTS automod_rule_stats (
+	guild_id BIGINT NOT NULL,
+	rule_id BIGINT references automod_rules(id) ON DELETE CASCADE NOT NULL,
+	trigger_typeid INT NOT NULL,
+
+	-- the hour the stats are for
+	t TIMESTAMP WITH TIME ZONE NOT NULL,
+
+	hits INT NOT NULL DEFAULT 0,
+	effects_applied INT NOT NULL DEFAULT 0,
+	false_positives INT NOT NULL DEFAULT 0,
+
+	PRIMARY KEY(rule_id, trigger_typeid, t)
+);
+`, `
+CREATE INDEX IF NOT EXISTS automod_rule_stats_guild_t_idx ON automod_rule_stats(guild_id, t);
 `}
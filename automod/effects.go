@@ -2,13 +2,20 @@ package automod
 
 import (
 	"context"
+	"database/sql"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jonas747/discordgo"
 	"github.com/jonas747/dstate"
+	"github.com/jonas747/retryableredis"
 	"github.com/jonas747/yagpdb/automod/models"
 	"github.com/jonas747/yagpdb/bot"
 	"github.com/jonas747/yagpdb/common"
 	"github.com/jonas747/yagpdb/common/scheduledevents2"
+	seventsmodels "github.com/jonas747/yagpdb/common/scheduledevents2/models"
+	"github.com/jonas747/yagpdb/common/templates"
 	"github.com/jonas747/yagpdb/moderation"
 	"github.com/volatiletech/null"
 	"github.com/volatiletech/sqlboiler/boil"
@@ -190,6 +197,12 @@ type AddViolationEffect struct{}
 
 type AddViolationEffectData struct {
 	Name string `valid:",1,100,trimspace"`
+
+	// Effects created before weights were added have none stored, so 0 is allowed and treated as 1
+	Weight int `valid:"0,1000"`
+
+	// Minutes it takes for the violation to lose 1 weight
+	Decay int `valid:"0,525600"`
 }
 
 func (vio *AddViolationEffect) Kind() RulePartType {
@@ -205,7 +218,7 @@ func (vio *AddViolationEffect) Name() (name string) {
 }
 
 func (vio *AddViolationEffect) Description() (description string) {
-	return "Adds a violation (use with violation tirggers)"
+	return "Adds a violation (use with violation tirggers), optionally with a weight other than 1 that decays by 1 every configured number of minutes"
 }
 
 func (vio *AddViolationEffect) UserSettings() []*SettingDef {
@@ -218,16 +231,47 @@ func (vio *AddViolationEffect) UserSettings() []*SettingDef {
 			Max:     50,
 			Default: "violation name",
 		},
+		&SettingDef{
+			Name:    "Weight (0 counts as 1)",
+			Key:     "Weight",
+			Kind:    SettingTypeInt,
+			Min:     0,
+			Max:     1000,
+			Default: 1,
+		},
+		&SettingDef{
+			Name:    "Lose 1 weight every (minutes, 0 = never)",
+			Key:     "Decay",
+			Kind:    SettingTypeInt,
+			Min:     0,
+			Max:     525600,
+			Default: 0,
+		},
 	}
 }
 
 func (vio *AddViolationEffect) Apply(ctxData *TriggeredRuleData, settings interface{}) error {
 	settingsCast := settings.(*AddViolationEffectData)
+
+	weight := settingsCast.Weight
+	if weight < 1 {
+		// older effects did not have a weight
+		weight = 1
+	}
+
+	now := time.Now()
 	violation := &models.AutomodViolation{
-		GuildID: ctxData.GS.ID,
-		UserID:  ctxData.MS.ID,
-		RuleID:  null.Int64From(ctxData.CurrentRule.Model.ID),
-		Name:    settingsCast.Name,
+		GuildID:   ctxData.GS.ID,
+		UserID:    ctxData.MS.ID,
+		RuleID:    null.Int64From(ctxData.CurrentRule.Model.ID),
+		CreatedAt: now,
+		Name:      settingsCast.Name,
+		Weight:    weight,
+	}
+
+	if settingsCast.Decay > 0 {
+		// decays_at is when the whole weight has decayed, see violationWeightAt
+		violation.DecaysAt = null.TimeFrom(now.Add(time.Duration(settingsCast.Decay) * time.Minute * time.Duration(weight)))
 	}
 
 	err := violation.InsertG(context.Background(), boil.Infer())
@@ -655,6 +699,8 @@ func (gf *GiveRoleEffect) Apply(ctxData *TriggeredRuleData, settings interface{}
 		// discord was not the cause of the error, in some cases even if the gateway times out the action is performed so just in case, scehdule the role removal
 	}
 
+	createRoleCase(ctxData, moderation.ActionGaveRole, settingsCast.Role, time.Second*time.Duration(settingsCast.Duration))
+
 	if settingsCast.Duration > 0 {
 		err := scheduledevents2.ScheduleRemoveRole(context.Background(), ctxData.GS.ID, ctxData.MS.ID, settingsCast.Role, time.Now().Add(time.Second*time.Duration(settingsCast.Duration)))
 		if err != nil {
@@ -664,3 +710,446 @@ func (gf *GiveRoleEffect) Apply(ctxData *TriggeredRuleData, settings interface{}
 
 	return nil
 }
+
+/////////////////////////////////////////////////////////////
+
+type LockdownEffect struct{}
+
+type LockdownEffectData struct {
+	Duration          int     `valid:"1,10080"`
+	VerificationLevel int     `valid:"0,4"`
+	Channels          []int64 `valid:"channel,true"`
+}
+
+func (ld *LockdownEffect) Kind() RulePartType {
+	return RulePartEffect
+}
+
+func (ld *LockdownEffect) DataType() interface{} {
+	return &LockdownEffectData{}
+}
+
+func (ld *LockdownEffect) UserSettings() []*SettingDef {
+	return []*SettingDef{
+		&SettingDef{
+			Name:    "Duration in minutes",
+			Key:     "Duration",
+			Default: 10,
+			Min:     1,
+			Max:     10080,
+			Kind:    SettingTypeInt,
+		},
+		&SettingDef{
+			Name:    "Raise verification level to (0 to not change, 1 = low, 2 = medium, 3 = high, 4 = highest)",
+			Key:     "VerificationLevel",
+			Default: 0,
+			Min:     0,
+			Max:     4,
+			Kind:    SettingTypeInt,
+		},
+		&SettingDef{
+			Name: "Deny @everyone sending messages in these channels",
+			Key:  "Channels",
+			Kind: SettingTypeMultiChannel,
+		},
+	}
+}
+
+func (ld *LockdownEffect) Name() (name string) {
+	return "Lockdown server"
+}
+
+func (ld *LockdownEffect) Description() (description string) {
+	return "Locks down the server for the specified duration by raising the verification level and/or denying @everyone from sending messages in the specified channels. Has no effect if the server is already locked down."
+}
+
+func (ld *LockdownEffect) Apply(ctxData *TriggeredRuleData, settings interface{}) error {
+	settingsCast := settings.(*LockdownEffectData)
+
+	duration := time.Duration(settingsCast.Duration) * time.Minute
+
+	// only one lockdown at a time, otherwise the lockdown state would be saved as the state to restore
+	var resp string
+	err := common.RedisPool.Do(retryableredis.FlatCmd(&resp, "SET", RedisKeyLockdown(ctxData.GS.ID), 1, "EX", int(duration/time.Second)+60, "NX"))
+	if err != nil || resp != "OK" {
+		return err
+	}
+
+	liftData := &LiftLockdownData{}
+
+	ctxData.GS.RLock()
+	currentLevel := int(ctxData.GS.Guild.VerificationLevel)
+	channels := make([]*discordgo.Channel, 0, len(settingsCast.Channels))
+	for _, cID := range settingsCast.Channels {
+		cs := ctxData.GS.Channel(false, cID)
+		if cs != nil {
+			channels = append(channels, cs.DGoCopy())
+		}
+	}
+	ctxData.GS.RUnlock()
+
+	if settingsCast.VerificationLevel > currentLevel {
+		level := discordgo.VerificationLevel(settingsCast.VerificationLevel)
+		_, err = common.BotSession.GuildEdit(ctxData.GS.ID, discordgo.GuildParams{VerificationLevel: &level})
+		if err == nil {
+			liftData.ChangedVerificationLevel = true
+			liftData.VerificationLevel = currentLevel
+		} else {
+			logger.WithError(err).WithField("guild", ctxData.GS.ID).Error("failed raising verification level")
+		}
+	}
+
+	// uses the same locking as the lock command, so it can also be lifted early with the unlock command
+	for _, v := range channels {
+		didLock, err := moderation.LockChannel(ctxData.GS.ID, v)
+		if err != nil {
+			logger.WithError(err).WithField("guild", ctxData.GS.ID).WithField("channel", v.ID).Error("failed locking down channel")
+			continue
+		}
+
+		// channels that were already locked are left for whoever locked them
+		if didLock {
+			liftData.Channels = append(liftData.Channels, v.ID)
+		}
+	}
+
+	return scheduledevents2.ScheduleEvent("amod2_lift_lockdown", ctxData.GS.ID, time.Now().Add(duration), liftData)
+}
+
+func (ld *LockdownEffect) MergeDuplicates(data []interface{}) interface{} {
+	return data[0] // no point in having duplicates of this
+}
+
+/////////////////////////////////////////////////////////////
+
+type RemoveRoleEffect struct{}
+
+func RedisKeyRemoveRoleLock(guildID, userID, roleID int64) string {
+	return "automod_remove_role:" + strconv.FormatInt(guildID, 10) + ":" + strconv.FormatInt(userID, 10) + ":" + strconv.FormatInt(roleID, 10)
+}
+
+type RemoveRoleEffectData struct {
+	Duration int `valid:"0,604800"`
+	Role     int64
+}
+
+func (rf *RemoveRoleEffect) Kind() RulePartType {
+	return RulePartEffect
+}
+
+func (rf *RemoveRoleEffect) DataType() interface{} {
+	return &RemoveRoleEffectData{}
+}
+
+func (rf *RemoveRoleEffect) UserSettings() []*SettingDef {
+	return []*SettingDef{
+		&SettingDef{
+			Name:    "Duration in seconds, 0 for permanent",
+			Key:     "Duration",
+			Default: 0,
+			Min:     0,
+			Max:     604800,
+			Kind:    SettingTypeInt,
+		},
+		&SettingDef{
+			Name: "Role",
+			Key:  "Role",
+			Kind: SettingTypeRole,
+		},
+	}
+}
+
+func (rf *RemoveRoleEffect) Name() (name string) {
+	return "Remove role"
+}
+
+func (rf *RemoveRoleEffect) Description() (description string) {
+	return "Removes the specified role from the user, optionally with a duration after which the role is given back to the user."
+}
+
+func (rf *RemoveRoleEffect) Apply(ctxData *TriggeredRuleData, settings interface{}) error {
+	settingsCast := settings.(*RemoveRoleEffectData)
+
+	ctxData.GS.RLock()
+	hasRole := common.ContainsInt64Slice(ctxData.MS.Roles, settingsCast.Role)
+	ctxData.GS.RUnlock()
+
+	if !hasRole {
+		return nil
+	}
+
+	// several rules can trigger at once and the state isn't updated until discord tells us, so only remove it once.
+	// MergeDuplicates can't be used for this as it would merge effects removing different roles into one
+	locked, err := common.TryLockRedisKey(RedisKeyRemoveRoleLock(ctxData.GS.ID, ctxData.MS.ID, settingsCast.Role), 10)
+	if err != nil || !locked {
+		return err
+	}
+
+	err = common.RemoveRoleDS(ctxData.MS, settingsCast.Role)
+	if err != nil {
+		if code, _ := common.DiscordError(err); code != 0 {
+			return nil // discord responded with a proper error, we know that shit didn't happen
+		}
+
+		// discord was not the cause of the error, in some cases even if the gateway times out the action is performed so just in case, schedule giving the role back
+	}
+
+	createRoleCase(ctxData, moderation.ActionRemovedRole, settingsCast.Role, time.Second*time.Duration(settingsCast.Duration))
+
+	if settingsCast.Duration > 0 {
+		err := scheduledevents2.ScheduleAddRole(context.Background(), ctxData.GS.ID, ctxData.MS.ID, settingsCast.Role, time.Now().Add(time.Second*time.Duration(settingsCast.Duration)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createRoleCase records a moderation case for roles given or removed by automod
+func createRoleCase(ctxData *TriggeredRuleData, action string, roleID int64, duration time.Duration) {
+	roleName := strconv.FormatInt(roleID, 10)
+	if role := ctxData.GS.RoleCopy(true, roleID); role != nil {
+		roleName = role.Name
+	}
+
+	reason := "Automoderator:\n" + ctxData.ConstructReason(true) + "\nRole: " + roleName
+	_, err := moderation.CreateCase(ctxData.GS.ID, action, common.BotUser, ctxData.MS.DGoUser(), reason, duration, "")
+	if err != nil {
+		logger.WithError(err).WithField("guild", ctxData.GS.ID).Error("failed creating moderation case")
+	}
+}
+
+/////////////////////////////////////////////////////////////
+
+type SlowmodeEffect struct{}
+
+type SlowmodeEffectData struct {
+	Channel   int64 `valid:"channel,true"`
+	RateLimit int   `valid:"0,21600"`
+	Duration  int   `valid:"0,10080"`
+}
+
+func (sm *SlowmodeEffect) Kind() RulePartType {
+	return RulePartEffect
+}
+
+func (sm *SlowmodeEffect) DataType() interface{} {
+	return &SlowmodeEffectData{}
+}
+
+func (sm *SlowmodeEffect) UserSettings() []*SettingDef {
+	return []*SettingDef{
+		&SettingDef{
+			Name: "Channel (none for the channel the rule was triggered in)",
+			Key:  "Channel",
+			Kind: SettingTypeChannel,
+		},
+		&SettingDef{
+			Name:    "Slowmode in seconds",
+			Key:     "RateLimit",
+			Default: 10,
+			Min:     0,
+			Max:     21600,
+			Kind:    SettingTypeInt,
+		},
+		&SettingDef{
+			Name:    "Duration in minutes, 0 for permanent",
+			Key:     "Duration",
+			Default: 10,
+			Min:     0,
+			Max:     10080,
+			Kind:    SettingTypeInt,
+		},
+	}
+}
+
+func (sm *SlowmodeEffect) Name() (name string) {
+	return "Set channel slowmode"
+}
+
+func (sm *SlowmodeEffect) Description() (description string) {
+	return "Sets the slowmode of a channel, optionally for a duration after which the previous slowmode is restored."
+}
+
+type ResetSlowmodeData struct {
+	ChannelID int64 `json:"channel_id"`
+	RateLimit int   `json:"rate_limit"`
+}
+
+func (sm *SlowmodeEffect) Apply(ctxData *TriggeredRuleData, settings interface{}) error {
+	settingsCast := settings.(*SlowmodeEffectData)
+
+	channelID := settingsCast.Channel
+	if channelID == 0 {
+		if ctxData.CS == nil {
+			return nil
+		}
+
+		channelID = ctxData.CS.ID
+	}
+
+	// if we already changed the slowmode of this channel, extend that instead so that the original slowmode is restored later
+	existing, err := seventsmodels.ScheduledEvents(qm.Where("event_name='amod2_reset_slowmode' AND guild_id = ? AND (data->>'channel_id')::bigint = ? AND processed = false", ctxData.GS.ID, channelID)).OneG(context.Background())
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	var original int
+	if existing == nil {
+		channel, err := common.BotSession.Channel(channelID)
+		if err != nil {
+			return err
+		}
+
+		original = channel.RateLimitPerUser
+		if original == settingsCast.RateLimit {
+			return nil
+		}
+	}
+
+	rateLimit := settingsCast.RateLimit
+	_, err = common.BotSession.ChannelEditComplex(channelID, &discordgo.ChannelEdit{
+		RateLimitPerUser: &rateLimit,
+	})
+	if err != nil {
+		return err
+	}
+
+	if settingsCast.Duration < 1 {
+		if existing != nil {
+			// permanent, dont reset it back
+			_, err = existing.DeleteG(context.Background())
+		}
+
+		return err
+	}
+
+	resetAt := time.Now().Add(time.Minute * time.Duration(settingsCast.Duration))
+	if existing != nil {
+		if existing.TriggersAt.Before(resetAt) {
+			existing.TriggersAt = resetAt
+			_, err = existing.UpdateG(context.Background(), boil.Whitelist("triggers_at"))
+		}
+
+		return err
+	}
+
+	return scheduledevents2.ScheduleEvent("amod2_reset_slowmode", ctxData.GS.ID, resetAt, &ResetSlowmodeData{
+		ChannelID: channelID,
+		RateLimit: original,
+	})
+}
+
+func (sm *SlowmodeEffect) MergeDuplicates(data []interface{}) interface{} {
+	return data[0] // no point in having duplicates of this
+}
+
+func (p *Plugin) handleResetSlowmode(evt *seventsmodels.ScheduledEvent, data interface{}) (retry bool, err error) {
+	dataCast := data.(*ResetSlowmodeData)
+
+	rateLimit := dataCast.RateLimit
+	_, err = common.BotSession.ChannelEditComplex(dataCast.ChannelID, &discordgo.ChannelEdit{
+		RateLimitPerUser: &rateLimit,
+	})
+
+	return scheduledevents2.CheckDiscordErrRetry(err), err
+}
+
+/////////////////////////////////////////////////////////////
+
+type SendMessageEffect struct{}
+
+type SendMessageEffectData struct {
+	Channel int64 `valid:"channel,true"`
+	DM      bool
+	Message string `valid:"template,2000"`
+}
+
+func (sm *SendMessageEffect) Kind() RulePartType {
+	return RulePartEffect
+}
+
+func (sm *SendMessageEffect) DataType() interface{} {
+	return &SendMessageEffectData{}
+}
+
+func (sm *SendMessageEffect) UserSettings() []*SettingDef {
+	return []*SettingDef{
+		&SettingDef{
+			Name:    "Message (template, .Reason, .RuleName, .RulesetName and .TriggerName are available)",
+			Key:     "Message",
+			Kind:    SettingTypeString,
+			Min:     1,
+			Max:     2000,
+			Default: "{{.User.Mention}} triggered {{.RuleName}}",
+		},
+		&SettingDef{
+			Name: "Channel (none for the channel the rule was triggered in)",
+			Key:  "Channel",
+			Kind: SettingTypeChannel,
+		},
+		&SettingDef{
+			Name: "Send as a DM to the user instead",
+			Key:  "DM",
+			Kind: SettingTypeBool,
+		},
+	}
+}
+
+func (sm *SendMessageEffect) Name() (name string) {
+	return "Send message"
+}
+
+func (sm *SendMessageEffect) Description() (description string) {
+	return "Sends a custom message to a channel or as a DM to the user"
+}
+
+func (sm *SendMessageEffect) Apply(ctxData *TriggeredRuleData, settings interface{}) error {
+	settingsCast := settings.(*SendMessageEffectData)
+
+	channelID := settingsCast.Channel
+	if channelID == 0 && ctxData.CS != nil {
+		channelID = ctxData.CS.ID
+	}
+
+	if channelID == 0 && !settingsCast.DM {
+		return nil
+	}
+
+	tmplCtx := templates.NewContext(ctxData.GS, ctxData.CS, ctxData.MS)
+	if ctxData.Message != nil {
+		tmplCtx.Msg = ctxData.Message
+	}
+
+	tmplCtx.Data["Reason"] = ctxData.ConstructReason(false)
+	tmplCtx.Data["RulesetName"] = ctxData.Ruleset.RSModel.Name
+	tmplCtx.Data["RuleName"] = ""
+	tmplCtx.Data["TriggerName"] = ""
+	if ctxData.CurrentRule != nil {
+		tmplCtx.Data["RuleName"] = ctxData.CurrentRule.Model.Name
+		for _, v := range ctxData.ActivatedTriggers {
+			if v.RuleModel.RuleID == ctxData.CurrentRule.Model.ID {
+				tmplCtx.Data["TriggerName"] = v.Part.Name()
+				break
+			}
+		}
+	}
+
+	executed, err := tmplCtx.Execute(settingsCast.Message)
+	if err != nil {
+		logger.WithError(err).WithField("guild", ctxData.GS.ID).Warn("failed executing automod send message template")
+		return nil
+	}
+
+	if strings.TrimSpace(executed) == "" {
+		return nil
+	}
+
+	if settingsCast.DM {
+		return bot.SendDM(ctxData.MS.ID, "**"+bot.GuildName(ctxData.GS.ID)+":** "+executed)
+	}
+
+	_, _, err = bot.SendMessageGS(ctxData.GS, channelID, executed)
+	return err
+}
@@ -0,0 +1,323 @@
+package automod
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jonas747/yagpdb/automod/models"
+	"github.com/volatiletech/sqlboiler/types"
+)
+
+// RulesetExportVersion is the current version of the ruleset export format, bump it when making incompatible changes
+const RulesetExportVersion = 1
+
+// RulesetExport is a serialised ruleset that can be imported on another server.
+// Parts are identified by their type ID in RulePartMap, which are stable.
+type RulesetExport struct {
+	Version  int    `json:"version"`
+	Name     string `json:"name"`
+	Enabled  bool   `json:"enabled"`
+	Simulate bool   `json:"simulate"`
+
+	// Only kept when importing if the channel exists on the server
+	SimulateLogChannel int64 `json:"simulate_log_channel,string"`
+
+	Conditions []*ExportedPart `json:"conditions"`
+	Rules      []*ExportedRule `json:"rules"`
+	Lists      []*ExportedList `json:"lists"`
+}
+
+type ExportedRule struct {
+	Name       string          `json:"name"`
+	Triggers   []*ExportedPart `json:"triggers"`
+	Conditions []*ExportedPart `json:"conditions"`
+	Effects    []*ExportedPart `json:"effects"`
+}
+
+type ExportedPart struct {
+	TypeID   int             `json:"type_id"`
+	Settings json.RawMessage `json:"settings"`
+}
+
+// ExportedList is a list referenced by one of the parts, the ID is only used to match it up with the part settings
+type ExportedList struct {
+	ID      int64    `json:"id"`
+	Name    string   `json:"name"`
+	Kind    int      `json:"kind"`
+	Content []string `json:"content"`
+}
+
+// ExportRuleset serialises the ruleset, it needs to have its rules, rule data and conditions loaded.
+// Only the lists referenced by the ruleset are included.
+func ExportRuleset(rs *models.AutomodRuleset, lists []*models.AutomodList) *RulesetExport {
+	export := &RulesetExport{
+		Version:            RulesetExportVersion,
+		Name:               rs.Name,
+		Enabled:            rs.Enabled,
+		Simulate:           rs.Simulate,
+		SimulateLogChannel: rs.SimulateLogChannel,
+	}
+
+	usedLists := make(map[int64]bool)
+	exportPart := func(typeID int, settings types.JSON) *ExportedPart {
+		for _, v := range partListIDs(typeID, settings) {
+			usedLists[v] = true
+		}
+
+		return &ExportedPart{
+			TypeID:   typeID,
+			Settings: json.RawMessage(settings),
+		}
+	}
+
+	for _, v := range rs.R.RulesetAutomodRulesetConditions {
+		export.Conditions = append(export.Conditions, exportPart(v.TypeID, v.Settings))
+	}
+
+	for _, rule := range rs.R.RulesetAutomodRules {
+		exportedRule := &ExportedRule{
+			Name: rule.Name,
+		}
+
+		for _, v := range rule.R.RuleAutomodRuleData {
+			part := exportPart(v.TypeID, v.Settings)
+			switch RulePartType(v.Kind) {
+			case RulePartTrigger:
+				exportedRule.Triggers = append(exportedRule.Triggers, part)
+			case RulePartCondition:
+				exportedRule.Conditions = append(exportedRule.Conditions, part)
+			case RulePartEffect:
+				exportedRule.Effects = append(exportedRule.Effects, part)
+			}
+		}
+
+		export.Rules = append(export.Rules, exportedRule)
+	}
+
+	for _, v := range lists {
+		if !usedLists[v.ID] {
+			continue
+		}
+
+		export.Lists = append(export.Lists, &ExportedList{
+			ID:      v.ID,
+			Name:    v.Name,
+			Kind:    v.Kind,
+			Content: v.Content,
+		})
+	}
+
+	return export
+}
+
+// partListIDs returns the IDs of the lists referenced in the settings of a part
+func partListIDs(typeID int, settings []byte) []int64 {
+	part, ok := RulePartMap[typeID]
+	if !ok {
+		return nil
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(settings, &decoded); err != nil {
+		return nil
+	}
+
+	var result []int64
+	for _, def := range part.UserSettings() {
+		if def.Kind != SettingTypeList {
+			continue
+		}
+
+		if f, ok := decoded[def.Key].(float64); ok && f != 0 {
+			result = append(result, int64(f))
+		}
+	}
+
+	return result
+}
+
+// ParseRulesetExport decodes and validates a ruleset export, it checks the part types, their kinds and the settings against
+// the bounds specified in UserSettings. Guild specific validation (roles, channels, limits) is up to the caller.
+func ParseRulesetExport(data []byte) (*RulesetExport, error) {
+	var export RulesetExport
+	err := json.Unmarshal(data, &export)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json: %v", err)
+	}
+
+	if export.Version < 1 || export.Version > RulesetExportVersion {
+		return nil, fmt.Errorf("unsupported export version %d", export.Version)
+	}
+
+	if export.Name == "" || utf8.RuneCountInString(export.Name) > 50 {
+		return nil, fmt.Errorf("ruleset name has to be between 1 and 50 characters")
+	}
+
+	if err = validateExportedParts(export.Conditions, RulePartCondition, "ruleset conditions"); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range export.Rules {
+		if rule.Name == "" || utf8.RuneCountInString(rule.Name) > 50 {
+			return nil, fmt.Errorf("rule #%d: name has to be between 1 and 50 characters", i+1)
+		}
+
+		if len(rule.Triggers)+len(rule.Conditions)+len(rule.Effects) > MaxRuleParts {
+			return nil, fmt.Errorf("rule %q: too many triggers/conditions/effects (max %d)", rule.Name, MaxRuleParts)
+		}
+
+		if err = validateExportedParts(rule.Triggers, RulePartTrigger, "rule "+rule.Name+" triggers"); err != nil {
+			return nil, err
+		}
+		if err = validateExportedParts(rule.Conditions, RulePartCondition, "rule "+rule.Name+" conditions"); err != nil {
+			return nil, err
+		}
+		if err = validateExportedParts(rule.Effects, RulePartEffect, "rule "+rule.Name+" effects"); err != nil {
+			return nil, err
+		}
+	}
+
+	listIDs := make(map[int64]bool)
+	for _, list := range export.Lists {
+		if list.Name == "" || utf8.RuneCountInString(list.Name) > 50 {
+			return nil, fmt.Errorf("list names has to be between 1 and 50 characters")
+		}
+
+		// same limit as the list editor, otherwise the list could not be saved from it again
+		if utf8.RuneCountInString(strings.Join(list.Content, "\n")) > MaxListContentLength {
+			return nil, fmt.Errorf("list %q: content can be at most %d characters", list.Name, MaxListContentLength)
+		}
+
+		listIDs[list.ID] = true
+	}
+
+	if err = checkExportedListRefs(export.Conditions, listIDs, "ruleset conditions"); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range export.Rules {
+		for _, parts := range [][]*ExportedPart{rule.Triggers, rule.Conditions, rule.Effects} {
+			if err = checkExportedListRefs(parts, listIDs, "rule "+rule.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &export, nil
+}
+
+// checkExportedListRefs makes sure all the lists referenced by the parts are included in the export
+func checkExportedListRefs(parts []*ExportedPart, listIDs map[int64]bool, where string) error {
+	for _, v := range parts {
+		for _, id := range partListIDs(v.TypeID, v.Settings) {
+			if !listIDs[id] {
+				return fmt.Errorf("%s: %q references list %d which is not included in the export", where, RulePartMap[v.TypeID].Name(), id)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateExportedParts(parts []*ExportedPart, kind RulePartType, where string) error {
+	for _, v := range parts {
+		part, ok := RulePartMap[v.TypeID]
+		if !ok {
+			return fmt.Errorf("%s: %v", where, &ErrUnknownTypeID{v.TypeID})
+		}
+
+		if part.Kind() != kind {
+			return fmt.Errorf("%s: %q is in the wrong place", where, part.Name())
+		}
+
+		dst := part.DataType()
+		if dst == nil {
+			continue
+		}
+
+		if len(v.Settings) > 0 {
+			if err := json.Unmarshal(v.Settings, dst); err != nil {
+				return fmt.Errorf("%s: %q has invalid settings: %v", where, part.Name(), err)
+			}
+		}
+
+		if err := checkSettingBounds(part, dst); err != nil {
+			return fmt.Errorf("%s: %q: %v", where, part.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// checkSettingBounds checks int and string settings against the min and max of their definitions
+func checkSettingBounds(part RulePart, settings interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(settings))
+	for _, def := range part.UserSettings() {
+		if def.Min == 0 && def.Max == 0 {
+			continue
+		}
+
+		field := v.FieldByName(def.Key)
+		if !field.IsValid() {
+			continue
+		}
+
+		switch def.Kind {
+		case SettingTypeInt:
+			if field.Kind() != reflect.Int && field.Kind() != reflect.Int64 {
+				continue
+			}
+
+			n := field.Int()
+			if n < int64(def.Min) || (def.Max != 0 && n > int64(def.Max)) {
+				return fmt.Errorf("%s has to be between %d and %d", def.Name, def.Min, def.Max)
+			}
+		case SettingTypeString:
+			if field.Kind() != reflect.String {
+				continue
+			}
+
+			n := utf8.RuneCountInString(field.String())
+			if n < def.Min || (def.Max != 0 && n > def.Max) {
+				return fmt.Errorf("%s has to be between %d and %d characters", def.Name, def.Min, def.Max)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RemapPartListIDs returns the part settings with the list references changed according to the mapping
+func RemapPartListIDs(part *ExportedPart, mapping map[int64]int64) (types.JSON, error) {
+	rp := RulePartMap[part.TypeID]
+	if len(part.Settings) < 1 {
+		return types.JSON("{}"), nil
+	}
+
+	var decoded map[string]interface{}
+	err := json.Unmarshal(part.Settings, &decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, def := range rp.UserSettings() {
+		if def.Kind != SettingTypeList {
+			continue
+		}
+
+		if f, ok := decoded[def.Key].(float64); ok && f != 0 {
+			newID, ok := mapping[int64(f)]
+			if !ok {
+				return nil, fmt.Errorf("list %d is not included in the export", int64(f))
+			}
+
+			decoded[def.Key] = newID
+		}
+	}
+
+	encoded, err := json.Marshal(decoded)
+	return types.JSON(encoded), err
+}
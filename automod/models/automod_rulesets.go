@@ -23,25 +23,31 @@ import (
 
 // AutomodRuleset is an object representing the database table.
 type AutomodRuleset struct {
-	ID      int64  `boil:"id" json:"id" toml:"id" yaml:"id"`
-	GuildID int64  `boil:"guild_id" json:"guild_id" toml:"guild_id" yaml:"guild_id"`
-	Name    string `boil:"name" json:"name" toml:"name" yaml:"name"`
-	Enabled bool   `boil:"enabled" json:"enabled" toml:"enabled" yaml:"enabled"`
+	ID                 int64  `boil:"id" json:"id" toml:"id" yaml:"id"`
+	GuildID            int64  `boil:"guild_id" json:"guild_id" toml:"guild_id" yaml:"guild_id"`
+	Name               string `boil:"name" json:"name" toml:"name" yaml:"name"`
+	Enabled            bool   `boil:"enabled" json:"enabled" toml:"enabled" yaml:"enabled"`
+	Simulate           bool   `boil:"simulate" json:"simulate" toml:"simulate" yaml:"simulate"`
+	SimulateLogChannel int64  `boil:"simulate_log_channel" json:"simulate_log_channel" toml:"simulate_log_channel" yaml:"simulate_log_channel"`
 
 	R *automodRulesetR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L automodRulesetL  `boil:"-" json:"-" toml:"-" yaml:"-"`
 }
 
 var AutomodRulesetColumns = struct {
-	ID      string
-	GuildID string
-	Name    string
-	Enabled string
+	ID                 string
+	GuildID            string
+	Name               string
+	Enabled            string
+	Simulate           string
+	SimulateLogChannel string
 }{
-	ID:      "id",
-	GuildID: "guild_id",
-	Name:    "name",
-	Enabled: "enabled",
+	ID:                 "id",
+	GuildID:            "guild_id",
+	Name:               "name",
+	Enabled:            "enabled",
+	Simulate:           "simulate",
+	SimulateLogChannel: "simulate_log_channel",
 }
 
 // Generated where
@@ -56,15 +62,19 @@ func (w whereHelperbool) GT(x bool) qm.QueryMod  { return qmhelper.Where(w.field
 func (w whereHelperbool) GTE(x bool) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GTE, x) }
 
 var AutomodRulesetWhere = struct {
-	ID      whereHelperint64
-	GuildID whereHelperint64
-	Name    whereHelperstring
-	Enabled whereHelperbool
+	ID                 whereHelperint64
+	GuildID            whereHelperint64
+	Name               whereHelperstring
+	Enabled            whereHelperbool
+	Simulate           whereHelperbool
+	SimulateLogChannel whereHelperint64
 }{
-	ID:      whereHelperint64{field: "\"automod_rulesets\".\"id\""},
-	GuildID: whereHelperint64{field: "\"automod_rulesets\".\"guild_id\""},
-	Name:    whereHelperstring{field: "\"automod_rulesets\".\"name\""},
-	Enabled: whereHelperbool{field: "\"automod_rulesets\".\"enabled\""},
+	ID:                 whereHelperint64{field: "\"automod_rulesets\".\"id\""},
+	GuildID:            whereHelperint64{field: "\"automod_rulesets\".\"guild_id\""},
+	Name:               whereHelperstring{field: "\"automod_rulesets\".\"name\""},
+	Enabled:            whereHelperbool{field: "\"automod_rulesets\".\"enabled\""},
+	Simulate:           whereHelperbool{field: "\"automod_rulesets\".\"simulate\""},
+	SimulateLogChannel: whereHelperint64{field: "\"automod_rulesets\".\"simulate_log_channel\""},
 }
 
 // AutomodRulesetRels is where relationship names are stored.
@@ -91,9 +101,9 @@ func (*automodRulesetR) NewStruct() *automodRulesetR {
 type automodRulesetL struct{}
 
 var (
-	automodRulesetAllColumns            = []string{"id", "guild_id", "name", "enabled"}
+	automodRulesetAllColumns            = []string{"id", "guild_id", "name", "enabled", "simulate", "simulate_log_channel"}
 	automodRulesetColumnsWithoutDefault = []string{"guild_id", "name", "enabled"}
-	automodRulesetColumnsWithDefault    = []string{"id"}
+	automodRulesetColumnsWithDefault    = []string{"id", "simulate", "simulate_log_channel"}
 	automodRulesetPrimaryKeyColumns     = []string{"id"}
 )
 
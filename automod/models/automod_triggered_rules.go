@@ -25,19 +25,22 @@ import (
 
 // AutomodTriggeredRule is an object representing the database table.
 type AutomodTriggeredRule struct {
-	ID            int64      `boil:"id" json:"id" toml:"id" yaml:"id"`
-	CreatedAt     time.Time  `boil:"created_at" json:"created_at" toml:"created_at" yaml:"created_at"`
-	ChannelID     int64      `boil:"channel_id" json:"channel_id" toml:"channel_id" yaml:"channel_id"`
-	ChannelName   string     `boil:"channel_name" json:"channel_name" toml:"channel_name" yaml:"channel_name"`
-	GuildID       int64      `boil:"guild_id" json:"guild_id" toml:"guild_id" yaml:"guild_id"`
-	TriggerID     null.Int64 `boil:"trigger_id" json:"trigger_id,omitempty" toml:"trigger_id" yaml:"trigger_id,omitempty"`
-	TriggerTypeid int        `boil:"trigger_typeid" json:"trigger_typeid" toml:"trigger_typeid" yaml:"trigger_typeid"`
-	RuleID        null.Int64 `boil:"rule_id" json:"rule_id,omitempty" toml:"rule_id" yaml:"rule_id,omitempty"`
-	RuleName      string     `boil:"rule_name" json:"rule_name" toml:"rule_name" yaml:"rule_name"`
-	RulesetName   string     `boil:"ruleset_name" json:"ruleset_name" toml:"ruleset_name" yaml:"ruleset_name"`
-	UserID        int64      `boil:"user_id" json:"user_id" toml:"user_id" yaml:"user_id"`
-	UserName      string     `boil:"user_name" json:"user_name" toml:"user_name" yaml:"user_name"`
-	Extradata     types.JSON `boil:"extradata" json:"extradata" toml:"extradata" yaml:"extradata"`
+	ID            int64            `boil:"id" json:"id" toml:"id" yaml:"id"`
+	CreatedAt     time.Time        `boil:"created_at" json:"created_at" toml:"created_at" yaml:"created_at"`
+	ChannelID     int64            `boil:"channel_id" json:"channel_id" toml:"channel_id" yaml:"channel_id"`
+	ChannelName   string           `boil:"channel_name" json:"channel_name" toml:"channel_name" yaml:"channel_name"`
+	GuildID       int64            `boil:"guild_id" json:"guild_id" toml:"guild_id" yaml:"guild_id"`
+	TriggerID     null.Int64       `boil:"trigger_id" json:"trigger_id,omitempty" toml:"trigger_id" yaml:"trigger_id,omitempty"`
+	TriggerTypeid int              `boil:"trigger_typeid" json:"trigger_typeid" toml:"trigger_typeid" yaml:"trigger_typeid"`
+	RuleID        null.Int64       `boil:"rule_id" json:"rule_id,omitempty" toml:"rule_id" yaml:"rule_id,omitempty"`
+	RuleName      string           `boil:"rule_name" json:"rule_name" toml:"rule_name" yaml:"rule_name"`
+	RulesetName   string           `boil:"ruleset_name" json:"ruleset_name" toml:"ruleset_name" yaml:"ruleset_name"`
+	UserID        int64            `boil:"user_id" json:"user_id" toml:"user_id" yaml:"user_id"`
+	UserName      string           `boil:"user_name" json:"user_name" toml:"user_name" yaml:"user_name"`
+	Extradata     types.JSON       `boil:"extradata" json:"extradata" toml:"extradata" yaml:"extradata"`
+	Simulated     bool             `boil:"simulated" json:"simulated" toml:"simulated" yaml:"simulated"`
+	EffectTypeids types.Int64Array `boil:"effect_typeids" json:"effect_typeids" toml:"effect_typeids" yaml:"effect_typeids"`
+	FalsePositive bool             `boil:"false_positive" json:"false_positive" toml:"false_positive" yaml:"false_positive"`
 
 	R *automodTriggeredRuleR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L automodTriggeredRuleL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -57,6 +60,9 @@ var AutomodTriggeredRuleColumns = struct {
 	UserID        string
 	UserName      string
 	Extradata     string
+	Simulated     string
+	EffectTypeids string
+	FalsePositive string
 }{
 	ID:            "id",
 	CreatedAt:     "created_at",
@@ -71,6 +77,9 @@ var AutomodTriggeredRuleColumns = struct {
 	UserID:        "user_id",
 	UserName:      "user_name",
 	Extradata:     "extradata",
+	Simulated:     "simulated",
+	EffectTypeids: "effect_typeids",
+	FalsePositive: "false_positive",
 }
 
 // Generated where
@@ -119,6 +128,29 @@ func (w whereHelpernull_Int64) GTE(x null.Int64) qm.QueryMod {
 	return qmhelper.Where(w.field, qmhelper.GTE, x)
 }
 
+type whereHelpertypes_Int64Array struct{ field string }
+
+func (w whereHelpertypes_Int64Array) EQ(x types.Int64Array) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, false, x)
+}
+func (w whereHelpertypes_Int64Array) NEQ(x types.Int64Array) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, true, x)
+}
+func (w whereHelpertypes_Int64Array) IsNull() qm.QueryMod    { return qmhelper.WhereIsNull(w.field) }
+func (w whereHelpertypes_Int64Array) IsNotNull() qm.QueryMod { return qmhelper.WhereIsNotNull(w.field) }
+func (w whereHelpertypes_Int64Array) LT(x types.Int64Array) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LT, x)
+}
+func (w whereHelpertypes_Int64Array) LTE(x types.Int64Array) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LTE, x)
+}
+func (w whereHelpertypes_Int64Array) GT(x types.Int64Array) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GT, x)
+}
+func (w whereHelpertypes_Int64Array) GTE(x types.Int64Array) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GTE, x)
+}
+
 var AutomodTriggeredRuleWhere = struct {
 	ID            whereHelperint64
 	CreatedAt     whereHelpertime_Time
@@ -133,6 +165,9 @@ var AutomodTriggeredRuleWhere = struct {
 	UserID        whereHelperint64
 	UserName      whereHelperstring
 	Extradata     whereHelpertypes_JSON
+	Simulated     whereHelperbool
+	EffectTypeids whereHelpertypes_Int64Array
+	FalsePositive whereHelperbool
 }{
 	ID:            whereHelperint64{field: "\"automod_triggered_rules\".\"id\""},
 	CreatedAt:     whereHelpertime_Time{field: "\"automod_triggered_rules\".\"created_at\""},
@@ -147,6 +182,9 @@ var AutomodTriggeredRuleWhere = struct {
 	UserID:        whereHelperint64{field: "\"automod_triggered_rules\".\"user_id\""},
 	UserName:      whereHelperstring{field: "\"automod_triggered_rules\".\"user_name\""},
 	Extradata:     whereHelpertypes_JSON{field: "\"automod_triggered_rules\".\"extradata\""},
+	Simulated:     whereHelperbool{field: "\"automod_triggered_rules\".\"simulated\""},
+	EffectTypeids: whereHelpertypes_Int64Array{field: "\"automod_triggered_rules\".\"effect_typeids\""},
+	FalsePositive: whereHelperbool{field: "\"automod_triggered_rules\".\"false_positive\""},
 }
 
 // AutomodTriggeredRuleRels is where relationship names are stored.
@@ -173,9 +211,9 @@ func (*automodTriggeredRuleR) NewStruct() *automodTriggeredRuleR {
 type automodTriggeredRuleL struct{}
 
 var (
-	automodTriggeredRuleAllColumns            = []string{"id", "created_at", "channel_id", "channel_name", "guild_id", "trigger_id", "trigger_typeid", "rule_id", "rule_name", "ruleset_name", "user_id", "user_name", "extradata"}
-	automodTriggeredRuleColumnsWithoutDefault = []string{"created_at", "channel_id", "channel_name", "guild_id", "trigger_id", "trigger_typeid", "rule_id", "rule_name", "ruleset_name", "user_id", "user_name", "extradata"}
-	automodTriggeredRuleColumnsWithDefault    = []string{"id"}
+	automodTriggeredRuleAllColumns            = []string{"id", "created_at", "channel_id", "channel_name", "guild_id", "trigger_id", "trigger_typeid", "rule_id", "rule_name", "ruleset_name", "user_id", "user_name", "extradata", "simulated", "effect_typeids", "false_positive"}
+	automodTriggeredRuleColumnsWithoutDefault = []string{"created_at", "channel_id", "channel_name", "guild_id", "trigger_id", "trigger_typeid", "rule_id", "rule_name", "ruleset_name", "user_id", "user_name", "extradata", "effect_typeids"}
+	automodTriggeredRuleColumnsWithDefault    = []string{"id", "simulated", "false_positive"}
 	automodTriggeredRulePrimaryKeyColumns     = []string{"id"}
 )
 
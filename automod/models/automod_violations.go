@@ -30,6 +30,8 @@ type AutomodViolation struct {
 	RuleID    null.Int64 `boil:"rule_id" json:"rule_id,omitempty" toml:"rule_id" yaml:"rule_id,omitempty"`
 	CreatedAt time.Time  `boil:"created_at" json:"created_at" toml:"created_at" yaml:"created_at"`
 	Name      string     `boil:"name" json:"name" toml:"name" yaml:"name"`
+	Weight    int        `boil:"weight" json:"weight" toml:"weight" yaml:"weight"`
+	DecaysAt  null.Time  `boil:"decays_at" json:"decays_at,omitempty" toml:"decays_at" yaml:"decays_at,omitempty"`
 
 	R *automodViolationR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L automodViolationL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -42,6 +44,8 @@ var AutomodViolationColumns = struct {
 	RuleID    string
 	CreatedAt string
 	Name      string
+	Weight    string
+	DecaysAt  string
 }{
 	ID:        "id",
 	GuildID:   "guild_id",
@@ -49,10 +53,35 @@ var AutomodViolationColumns = struct {
 	RuleID:    "rule_id",
 	CreatedAt: "created_at",
 	Name:      "name",
+	Weight:    "weight",
+	DecaysAt:  "decays_at",
 }
 
 // Generated where
 
+type whereHelpernull_Time struct{ field string }
+
+func (w whereHelpernull_Time) EQ(x null.Time) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, false, x)
+}
+func (w whereHelpernull_Time) NEQ(x null.Time) qm.QueryMod {
+	return qmhelper.WhereNullEQ(w.field, true, x)
+}
+func (w whereHelpernull_Time) IsNull() qm.QueryMod    { return qmhelper.WhereIsNull(w.field) }
+func (w whereHelpernull_Time) IsNotNull() qm.QueryMod { return qmhelper.WhereIsNotNull(w.field) }
+func (w whereHelpernull_Time) LT(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LT, x)
+}
+func (w whereHelpernull_Time) LTE(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LTE, x)
+}
+func (w whereHelpernull_Time) GT(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GT, x)
+}
+func (w whereHelpernull_Time) GTE(x null.Time) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GTE, x)
+}
+
 var AutomodViolationWhere = struct {
 	ID        whereHelperint64
 	GuildID   whereHelperint64
@@ -60,6 +89,8 @@ var AutomodViolationWhere = struct {
 	RuleID    whereHelpernull_Int64
 	CreatedAt whereHelpertime_Time
 	Name      whereHelperstring
+	Weight    whereHelperint
+	DecaysAt  whereHelpernull_Time
 }{
 	ID:        whereHelperint64{field: "\"automod_violations\".\"id\""},
 	GuildID:   whereHelperint64{field: "\"automod_violations\".\"guild_id\""},
@@ -67,6 +98,8 @@ var AutomodViolationWhere = struct {
 	RuleID:    whereHelpernull_Int64{field: "\"automod_violations\".\"rule_id\""},
 	CreatedAt: whereHelpertime_Time{field: "\"automod_violations\".\"created_at\""},
 	Name:      whereHelperstring{field: "\"automod_violations\".\"name\""},
+	Weight:    whereHelperint{field: "\"automod_violations\".\"weight\""},
+	DecaysAt:  whereHelpernull_Time{field: "\"automod_violations\".\"decays_at\""},
 }
 
 // AutomodViolationRels is where relationship names are stored.
@@ -90,9 +123,9 @@ func (*automodViolationR) NewStruct() *automodViolationR {
 type automodViolationL struct{}
 
 var (
-	automodViolationAllColumns            = []string{"id", "guild_id", "user_id", "rule_id", "created_at", "name"}
-	automodViolationColumnsWithoutDefault = []string{"guild_id", "user_id", "rule_id", "created_at", "name"}
-	automodViolationColumnsWithDefault    = []string{"id"}
+	automodViolationAllColumns            = []string{"id", "guild_id", "user_id", "rule_id", "created_at", "name", "weight", "decays_at"}
+	automodViolationColumnsWithoutDefault = []string{"guild_id", "user_id", "rule_id", "created_at", "name", "decays_at"}
+	automodViolationColumnsWithDefault    = []string{"id", "weight"}
 	automodViolationPrimaryKeyColumns     = []string{"id"}
 )
 
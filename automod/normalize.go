@@ -0,0 +1,110 @@
+package automod
+
+import (
+	"strings"
+	"unicode"
+)
+
+// confusables maps commonly abused lookalike characters to their ascii counterparts
+var confusables = map[rune]rune{
+	// cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x',
+	'ѕ': 's', 'і': 'i', 'ї': 'i', 'ј': 'j', 'ԁ': 'd', 'һ': 'h', 'ӏ': 'l', 'ԛ': 'q', 'ԝ': 'w', 'ь': 'b', 'п': 'n', 'г': 'r',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T', 'Х': 'X', 'У': 'Y',
+	'Ѕ': 'S', 'І': 'I', 'Ј': 'J', 'Ԁ': 'D', 'Ԛ': 'Q', 'Ԝ': 'W',
+
+	// greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M', 'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T',
+	'Υ': 'Y', 'Χ': 'X',
+
+	// latin lookalikes
+	'ı': 'i', 'ȷ': 'j', 'ɡ': 'g', 'ɑ': 'a', 'ʟ': 'l', 'ℓ': 'l', 'ⅰ': 'i', 'ⅼ': 'l', 'ⅽ': 'c', 'ⅾ': 'd', 'ⅿ': 'm',
+	'ᴀ': 'a', 'ʙ': 'b', 'ᴄ': 'c', 'ᴅ': 'd', 'ᴇ': 'e', 'ꜰ': 'f', 'ɢ': 'g', 'ʜ': 'h', 'ɪ': 'i', 'ᴊ': 'j', 'ᴋ': 'k', 'ᴍ': 'm',
+	'ɴ': 'n', 'ᴏ': 'o', 'ᴘ': 'p', 'ʀ': 'r', 'ꜱ': 's', 'ᴛ': 't', 'ᴜ': 'u', 'ᴠ': 'v', 'ᴡ': 'w', 'ʏ': 'y', 'ᴢ': 'z',
+}
+
+// isInvisibleRune returns true for zero width and other invisible formatting characters
+func isInvisibleRune(r rune) bool {
+	switch r {
+	case 0x00ad, 0x034f, 0x061c, 0x115f, 0x1160, 0x17b4, 0x17b5, 0x180e, 0x3164, 0xfeff, 0xffa0:
+		return true
+	}
+
+	return (r >= 0x200b && r <= 0x200f) || (r >= 0x202a && r <= 0x202e) || (r >= 0x2060 && r <= 0x206f)
+}
+
+// NormalizeConfusables folds lookalike characters (homoglyphs, fullwidth and mathematical letters) into ascii
+// and removes zero width characters and combining marks, so that "ѕ" matches "s"
+func NormalizeConfusables(input string) string {
+	var out strings.Builder
+	out.Grow(len(input))
+
+	for _, r := range input {
+		if isInvisibleRune(r) || unicode.In(r, unicode.Mn, unicode.Me) {
+			continue
+		}
+
+		switch {
+		case r >= 0xff01 && r <= 0xff5e:
+			// fullwidth ascii
+			r -= 0xfee0
+		case r == 0x3000:
+			// ideographic space
+			r = ' '
+		case r >= 0x1d400 && r <= 0x1d6a3:
+			// mathematical alphanumeric letters, 26 upper case followed by 26 lower case for each style
+			offset := (r - 0x1d400) % 52
+			if offset < 26 {
+				r = 'A' + offset
+			} else {
+				r = 'a' + offset - 26
+			}
+		case r >= 0x1d7ce && r <= 0x1d7ff:
+			// mathematical digits
+			r = '0' + (r-0x1d7ce)%10
+		case r >= 0x24b6 && r <= 0x24cf:
+			// circled upper case letters
+			r = 'A' + r - 0x24b6
+		case r >= 0x24d0 && r <= 0x24e9:
+			// circled lower case letters
+			r = 'a' + r - 0x24d0
+		default:
+			if replacement, ok := confusables[r]; ok {
+				r = replacement
+			}
+		}
+
+		out.WriteRune(r)
+	}
+
+	return out.String()
+}
+
+// MaxStackedCombiningMarks returns the highest number of combining marks attached to a single character in the input
+func MaxStackedCombiningMarks(input string) int {
+	highest := 0
+	current := 0
+	for _, r := range input {
+		if unicode.In(r, unicode.Mn, unicode.Me) {
+			current++
+			if current > highest {
+				highest = current
+			}
+			continue
+		}
+
+		current = 0
+	}
+
+	return highest
+}
+
+func normalizeWordList(words []string) []string {
+	result := make([]string, len(words))
+	for i, w := range words {
+		result[i] = NormalizeConfusables(w)
+	}
+
+	return result
+}
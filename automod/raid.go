@@ -0,0 +1,161 @@
+package automod
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/retryableredis"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/scheduledevents2"
+	seventsmodels "github.com/jonas747/yagpdb/common/scheduledevents2/models"
+	"github.com/jonas747/yagpdb/moderation"
+)
+
+const (
+	// joins older than this are forgotten, this is also the max interval of the join raid trigger
+	maxTrackedJoinAge = time.Hour
+	// max number of joins tracked per guild
+	maxTrackedJoins = 1000
+)
+
+type trackedJoin struct {
+	UserID int64
+	At     time.Time
+}
+
+// joinTracker keeps track of recent joins in each guild, used by the join raid trigger
+type joinTracker struct {
+	mu     sync.Mutex
+	guilds map[int64][]*trackedJoin
+}
+
+var recentJoins = &joinTracker{
+	guilds: make(map[int64][]*trackedJoin),
+}
+
+func (jt *joinTracker) add(guildID int64, userID int64, at time.Time) {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	joins := append(jt.guilds[guildID], &trackedJoin{UserID: userID, At: at})
+
+	// remove old entries
+	start := 0
+	for i, v := range joins {
+		if at.Sub(v.At) < maxTrackedJoinAge && len(joins)-i <= maxTrackedJoins {
+			break
+		}
+
+		start = i + 1
+	}
+
+	jt.guilds[guildID] = joins[start:]
+}
+
+// count returns the number of joins within the duration, optionally only counting accounts younger than maxAccountAge
+func (jt *joinTracker) count(guildID int64, within time.Duration, maxAccountAge time.Duration) int {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	now := time.Now()
+
+	n := 0
+	joins := jt.guilds[guildID]
+	for i := len(joins) - 1; i >= 0; i-- {
+		if now.Sub(joins[i].At) > within {
+			break
+		}
+
+		if maxAccountAge > 0 && now.Sub(bot.SnowflakeToTime(joins[i].UserID)) > maxAccountAge {
+			continue
+		}
+
+		n++
+	}
+
+	return n
+}
+
+// removeStale removes guilds with no recent joins from the tracker
+func (jt *joinTracker) removeStale() {
+	jt.mu.Lock()
+	defer jt.mu.Unlock()
+
+	now := time.Now()
+	for k, v := range jt.guilds {
+		if len(v) < 1 || now.Sub(v[len(v)-1].At) > maxTrackedJoinAge {
+			delete(jt.guilds, k)
+		}
+	}
+}
+
+func (p *Plugin) runJoinTrackerCleanup() {
+	ticker := time.NewTicker(time.Minute * 10)
+	for {
+		<-ticker.C
+		recentJoins.removeStale()
+	}
+}
+
+func RedisKeyLockdown(guildID int64) string {
+	return "automod_lockdown:" + strconv.FormatInt(guildID, 10)
+}
+
+type LiftLockdownData struct {
+	ChangedVerificationLevel bool
+	VerificationLevel        int
+
+	// The channels locked by the lockdown, the previous overwrites are stored by moderation.LockChannel
+	Channels []int64
+}
+
+// hasJoinRaidRule returns true if any of the enabled rulesets has a join raid trigger
+func hasJoinRaidRule(rulesets []*ParsedRuleset) bool {
+	for _, rs := range rulesets {
+		if !rs.RSModel.Enabled {
+			continue
+		}
+
+		for _, rule := range rs.Rules {
+			for _, trig := range rule.Triggers {
+				if _, ok := trig.Part.(*JoinRaidTrigger); ok {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// handleLiftLockdown restores the verification level and channel overwrites to what they were before the lockdown
+func (p *Plugin) handleLiftLockdown(evt *seventsmodels.ScheduledEvent, data interface{}) (retry bool, err error) {
+	dataCast := data.(*LiftLockdownData)
+
+	gs := bot.State.Guild(true, evt.GuildID)
+	if gs == nil {
+		return false, nil
+	}
+
+	if dataCast.ChangedVerificationLevel {
+		level := discordgo.VerificationLevel(dataCast.VerificationLevel)
+		_, err = common.BotSession.GuildEdit(evt.GuildID, discordgo.GuildParams{VerificationLevel: &level})
+		if err != nil {
+			return scheduledevents2.CheckDiscordErrRetry(err), err
+		}
+	}
+
+	for _, v := range dataCast.Channels {
+		// deleted channels are ignored by UnlockChannel
+		_, err = moderation.UnlockChannel(evt.GuildID, v)
+		if err != nil {
+			return scheduledevents2.CheckDiscordErrRetry(err), err
+		}
+	}
+
+	err = common.RedisPool.Do(retryableredis.Cmd(nil, "DEL", RedisKeyLockdown(evt.GuildID)))
+	return false, err
+}
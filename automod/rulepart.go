@@ -3,10 +3,12 @@ package automod
 import (
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/jonas747/discordgo"
 	"github.com/jonas747/dstate"
 	"github.com/jonas747/yagpdb/automod/models"
+	"github.com/jonas747/yagpdb/bot"
 )
 
 // maps rule part indentifiers to actual condition types
@@ -45,6 +47,11 @@ var RulePartMap = map[int]RulePart{
 	30: &MemberJoinTrigger{},
 	31: &MessageAttachmentTrigger{},
 	32: &MessageAttachmentTrigger{RequiresAttachment: true},
+	33: &DuplicateMessageTrigger{CrossChannel: false},
+	34: &DuplicateMessageTrigger{CrossChannel: true},
+	35: &EmojiFloodTrigger{},
+	36: &ZalgoTrigger{},
+	37: &JoinRaidTrigger{},
 
 	// Conditions 2xx
 	200: &MemberRolesCondition{Blacklist: true},
@@ -73,6 +80,10 @@ var RulePartMap = map[int]RulePart{
 	307: &ResetViolationsEffect{},
 	308: &DeleteMessagesEffect{},
 	309: &GiveRoleEffect{},
+	310: &LockdownEffect{},
+	311: &RemoveRoleEffect{},
+	312: &SlowmodeEffect{},
+	313: &SendMessageEffect{},
 }
 
 var InverseRulePartMap = make(map[RulePart]int)
@@ -171,6 +182,18 @@ type TriggeredRuleData struct {
 
 	// Gets added to when we recurse using +violation
 	PreviousReasons []string
+
+	// Only set when testing rules, overrides the account creation time otherwise derived from the users ID
+	AccountCreatedOverride time.Time
+}
+
+// AccountCreated returns when the account of the member was created
+func (t *TriggeredRuleData) AccountCreated() time.Time {
+	if !t.AccountCreatedOverride.IsZero() {
+		return t.AccountCreatedOverride
+	}
+
+	return bot.SnowflakeToTime(t.MS.ID)
 }
 
 func (t *TriggeredRuleData) Clone() *TriggeredRuleData {
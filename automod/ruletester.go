@@ -0,0 +1,201 @@
+package automod
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate"
+	"github.com/jonas747/yagpdb/common"
+)
+
+// RuleTestInput describes a fake message to run through the automod rules
+type RuleTestInput struct {
+	Content   string   `json:"content"`
+	ChannelID int64    `json:"channel_id,string"`
+	Roles     []string `json:"roles"`
+	Bot       bool     `json:"bot"`
+
+	// Ages are in minutes
+	AccountAge int `json:"account_age"`
+	MemberAge  int `json:"member_age"`
+}
+
+// RuleTestResult is the outcome of testing a message against a single ruleset, nothing in here was actually applied
+type RuleTestResult struct {
+	RulesetID        int64  `json:"ruleset_id"`
+	RulesetName      string `json:"ruleset_name"`
+	Enabled          bool   `json:"enabled"`
+	Simulate         bool   `json:"simulate"`
+	ConditionsPassed bool   `json:"conditions_passed"`
+
+	TriggeredRules []*RuleTestTriggeredRule `json:"triggered_rules"`
+}
+
+type RuleTestTriggeredRule struct {
+	RuleID   int64    `json:"rule_id"`
+	RuleName string   `json:"rule_name"`
+	Trigger  string   `json:"trigger"`
+	Effects  []string `json:"effects"`
+}
+
+// NewTestMember creates a fake member from the test input, the account age is passed to TestMessage separately
+func NewTestMember(gs *dstate.GuildState, input *RuleTestInput) *dstate.MemberState {
+	roles := make([]int64, 0, len(input.Roles))
+	for _, v := range input.Roles {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err == nil {
+			roles = append(roles, parsed)
+		}
+	}
+
+	now := time.Now()
+
+	return &dstate.MemberState{
+		ID:            snowflakeFromTime(now),
+		Guild:         gs,
+		Username:      "automod-test",
+		Discriminator: 1,
+		Bot:           input.Bot,
+		Roles:         roles,
+		JoinedAt:      now.Add(-time.Duration(input.MemberAge) * time.Minute),
+	}
+}
+
+func snowflakeFromTime(t time.Time) int64 {
+	ms := t.UnixNano() / int64(time.Millisecond)
+	return (ms - snowflake.Epoch) << 22
+}
+
+var (
+	testUserMentionRegex = regexp.MustCompile(`<@!?(\d+)>`)
+	testRoleMentionRegex = regexp.MustCompile(`<@&(\d+)>`)
+)
+
+// TestMessage runs a fake message with the provided content through the message triggers of the rulesets,
+// disabled rulesets are also checked. No effects are applied and nothing is logged.
+//
+// Triggers that look at the channel history (spam, slowmode and so on) are checked against the current state of the channel.
+// If accountCreated is not zero it's used as the creation time of the members account instead of the one from their ID.
+func (p *Plugin) TestMessage(rulesets []*ParsedRuleset, ms *dstate.MemberState, cs *dstate.ChannelState, content string, accountCreated time.Time) []*RuleTestResult {
+	msg := &discordgo.Message{
+		GuildID:   cs.Guild.ID,
+		ChannelID: cs.ID,
+		Content:   content,
+		Author: &discordgo.User{
+			ID:            ms.ID,
+			Username:      ms.Username,
+			Discriminator: strconv.Itoa(int(ms.Discriminator)),
+			Bot:           ms.Bot,
+		},
+	}
+
+	for _, v := range testUserMentionRegex.FindAllStringSubmatch(content, -1) {
+		parsed, _ := strconv.ParseInt(v[1], 10, 64)
+		msg.Mentions = append(msg.Mentions, &discordgo.User{ID: parsed})
+	}
+
+	for _, v := range testRoleMentionRegex.FindAllStringSubmatch(content, -1) {
+		parsed, _ := strconv.ParseInt(v[1], 10, 64)
+		msg.MentionRoles = append(msg.MentionRoles, parsed)
+	}
+
+	stripped := PrepareMessageForWordCheck(content)
+	checkF := func(trig *ParsedPart) (activated bool, err error) {
+		cast, ok := trig.Part.(MessageTrigger)
+		if !ok {
+			return false, nil
+		}
+
+		return cast.CheckMessage(ms, cs, msg, stripped, trig.ParsedSettings)
+	}
+
+	results := make([]*RuleTestResult, 0, len(rulesets))
+	for _, rs := range rulesets {
+		result := &RuleTestResult{
+			RulesetID:   rs.RSModel.ID,
+			RulesetName: rs.RSModel.Name,
+			Enabled:     rs.RSModel.Enabled,
+			Simulate:    rs.RSModel.Simulate,
+		}
+		results = append(results, result)
+
+		ctxData := &TriggeredRuleData{
+			MS:      ms,
+			CS:      cs,
+			GS:      cs.Guild,
+			Plugin:  p,
+			Ruleset: rs,
+
+			Message: msg,
+
+			AccountCreatedOverride: accountCreated,
+		}
+
+		if !p.CheckConditions(ctxData, rs.ParsedConditions) {
+			continue
+		}
+		result.ConditionsPassed = true
+
+		triggeredRules, activatedTriggers := p.checkRulesetTriggers(ctxData, rs, checkF)
+		for i, rule := range triggeredRules {
+			triggered := &RuleTestTriggeredRule{
+				RuleID:   rule.Model.ID,
+				RuleName: rule.Model.Name,
+				Trigger:  activatedTriggers[i].Part.Name(),
+				Effects:  make([]string, 0, len(rule.Effects)),
+			}
+
+			for _, effect := range rule.Effects {
+				triggered.Effects = append(triggered.Effects, effect.Part.Name())
+			}
+
+			result.TriggeredRules = append(result.TriggeredRules, triggered)
+		}
+	}
+
+	return results
+}
+
+// FormatRuleTestResults returns a human readable summary of the test results, suitable for sending in discord
+func FormatRuleTestResults(results []*RuleTestResult) string {
+	if len(results) < 1 {
+		return "No automod v2 rulesets set up on this server"
+	}
+
+	out := &strings.Builder{}
+	for _, v := range results {
+		status := ""
+		if !v.Enabled {
+			status = " (disabled)"
+		} else if v.Simulate {
+			status = " (simulating)"
+		}
+
+		if !v.ConditionsPassed {
+			out.WriteString(fmt.Sprintf("**%s**%s: ruleset conditions not met\n", v.RulesetName, status))
+			continue
+		}
+
+		if len(v.TriggeredRules) < 1 {
+			out.WriteString(fmt.Sprintf("**%s**%s: no rules triggered\n", v.RulesetName, status))
+			continue
+		}
+
+		out.WriteString(fmt.Sprintf("**%s**%s:\n", v.RulesetName, status))
+		for _, r := range v.TriggeredRules {
+			effects := "no effects"
+			if len(r.Effects) > 0 {
+				effects = "would apply: `" + strings.Join(r.Effects, "`, `") + "`"
+			}
+
+			out.WriteString(fmt.Sprintf("- R:`%s` T:`%s` - %s\n", r.RuleName, r.Trigger, effects))
+		}
+	}
+
+	return common.CutStringShort(out.String(), 2000)
+}
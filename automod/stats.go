@@ -0,0 +1,149 @@
+package automod
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jonas747/yagpdb/automod/models"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/backgroundworkers"
+	"github.com/volatiletech/sqlboiler/boil"
+)
+
+const (
+	// how long the hourly rule stats are kept
+	RuleStatsRetention = time.Hour * 24 * 30
+	MaxRuleStatsDays   = 30
+)
+
+// incrRuleStats adds to the hourly stats bucket of a rule and trigger
+func incrRuleStats(exec boil.ContextExecutor, guildID, ruleID int64, triggerTypeID int, t time.Time, hits, effectsApplied, falsePositives int) error {
+	const q = `INSERT INTO automod_rule_stats (guild_id, rule_id, trigger_typeid, t, hits, effects_applied, false_positives)
+VALUES ($1, $2, $3, date_trunc('hour', $4::timestamptz), $5, $6, $7)
+ON CONFLICT (rule_id, trigger_typeid, t)
+DO UPDATE SET
+	hits = automod_rule_stats.hits + EXCLUDED.hits,
+	effects_applied = automod_rule_stats.effects_applied + EXCLUDED.effects_applied,
+	false_positives = automod_rule_stats.false_positives + EXCLUDED.false_positives;`
+
+	_, err := exec.ExecContext(context.Background(), q, guildID, ruleID, triggerTypeID, t, hits, effectsApplied, falsePositives)
+	return err
+}
+
+// recordTriggeredRuleStats records the hits of rules that were triggered, the applied effects are counted as they succeed
+func recordTriggeredRuleStats(exec boil.ContextExecutor, entries []*models.AutomodTriggeredRule) error {
+	for _, v := range entries {
+		if !v.RuleID.Valid {
+			continue
+		}
+
+		err := incrRuleStats(exec, v.GuildID, v.RuleID.Int64, v.TriggerTypeid, time.Now(), 1, 0, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type RuleStatsTotal struct {
+	RuleID         int64  `json:"rule_id"`
+	RuleName       string `json:"rule_name"`
+	RulesetName    string `json:"ruleset_name"`
+	TriggerTypeID  int    `json:"trigger_typeid"`
+	TriggerName    string `json:"trigger_name"`
+	Hits           int64  `json:"hits"`
+	EffectsApplied int64  `json:"effects_applied"`
+	FalsePositives int64  `json:"false_positives"`
+}
+
+type RuleStatsHour struct {
+	T              time.Time `json:"t"`
+	Hits           int64     `json:"hits"`
+	EffectsApplied int64     `json:"effects_applied"`
+	FalsePositives int64     `json:"false_positives"`
+}
+
+// RetrieveRuleStats returns the per rule and trigger totals, and the hourly totals for the whole server within the last n days
+func RetrieveRuleStats(ctx context.Context, guildID int64, days int) (totals []*RuleStatsTotal, hourly []*RuleStatsHour, err error) {
+	since := time.Now().Add(-time.Hour * 24 * time.Duration(days))
+
+	const qTotals = `SELECT s.rule_id, r.name, rs.name, s.trigger_typeid, sum(s.hits), sum(s.effects_applied), sum(s.false_positives)
+FROM automod_rule_stats s
+INNER JOIN automod_rules r ON r.id = s.rule_id
+INNER JOIN automod_rulesets rs ON rs.id = r.ruleset_id
+WHERE s.guild_id = $1 AND s.t > $2
+GROUP BY s.rule_id, r.name, rs.name, s.trigger_typeid
+ORDER BY sum(s.hits) DESC;`
+
+	rows, err := common.PQ.QueryContext(ctx, qTotals, guildID, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry RuleStatsTotal
+		err = rows.Scan(&entry.RuleID, &entry.RuleName, &entry.RulesetName, &entry.TriggerTypeID, &entry.Hits, &entry.EffectsApplied, &entry.FalsePositives)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if part, ok := RulePartMap[entry.TriggerTypeID]; ok {
+			entry.TriggerName = part.Name()
+		}
+
+		totals = append(totals, &entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	const qHourly = `SELECT t, sum(hits), sum(effects_applied), sum(false_positives)
+FROM automod_rule_stats
+WHERE guild_id = $1 AND t > $2
+GROUP BY t
+ORDER BY t ASC;`
+
+	hourlyRows, err := common.PQ.QueryContext(ctx, qHourly, guildID, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer hourlyRows.Close()
+
+	for hourlyRows.Next() {
+		var entry RuleStatsHour
+		err = hourlyRows.Scan(&entry.T, &entry.Hits, &entry.EffectsApplied, &entry.FalsePositives)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		hourly = append(hourly, &entry)
+	}
+
+	return totals, hourly, hourlyRows.Err()
+}
+
+var _ backgroundworkers.BackgroundWorkerPlugin = (*Plugin)(nil)
+
+func (p *Plugin) RunBackgroundWorker() {
+	go runRuleStatsCleanup()
+}
+
+func (p *Plugin) StopBackgroundWorker(wg *sync.WaitGroup) {
+	wg.Done()
+}
+
+// runRuleStatsCleanup periodically deletes rule stats older than RuleStatsRetention
+func runRuleStatsCleanup() {
+	ticker := time.NewTicker(time.Hour)
+	for {
+		_, err := common.PQ.Exec("DELETE FROM automod_rule_stats WHERE t < $1", time.Now().Add(-RuleStatsRetention))
+		if err != nil {
+			logger.WithError(err).Error("failed cleaning up old automod rule stats")
+		}
+
+		<-ticker.C
+	}
+}
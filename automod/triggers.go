@@ -143,7 +143,8 @@ type WordListTrigger struct {
 	Blacklist bool
 }
 type WorldListTriggerData struct {
-	ListID int64
+	ListID    int64
+	Normalize bool
 }
 
 func (wl *WordListTrigger) Kind() RulePartType {
@@ -177,6 +178,11 @@ func (wl *WordListTrigger) UserSettings() []*SettingDef {
 			Key:  "ListID",
 			Kind: SettingTypeList,
 		},
+		&SettingDef{
+			Name: "Normalize lookalike characters (e.g homoglyphs, fullwidth and zero width characters)",
+			Key:  "Normalize",
+			Kind: SettingTypeBool,
+		},
 	}
 }
 
@@ -189,10 +195,15 @@ func (wl *WordListTrigger) CheckMessage(ms *dstate.MemberState, cs *dstate.Chann
 	}
 
 	messageFields := strings.Fields(mdStripped)
+	words := list.Content
+	if dataCast.Normalize {
+		messageFields = strings.Fields(PrepareMessageForWordCheck(NormalizeConfusables(m.Content)))
+		words = list.NormalizedContent
+	}
 
 	for _, mf := range messageFields {
 		contained := false
-		for _, w := range list.Content {
+		for _, w := range words {
 			if strings.EqualFold(mf, w) {
 				if wl.Blacklist {
 					// contains a blacklisted word, trigger
@@ -342,7 +353,7 @@ func (vt *ViolationsTrigger) Name() string {
 }
 
 func (vt *ViolationsTrigger) Description() string {
-	return "Triggers when a user has more than x violations within y minutes."
+	return "Triggers when a user has more than x violations within y minutes. Violations with a weight count as that many violations, minus the weight they have decayed by."
 }
 
 func (vt *ViolationsTrigger) UserSettings() []*SettingDef {
@@ -356,7 +367,7 @@ func (vt *ViolationsTrigger) UserSettings() []*SettingDef {
 			Max:     50,
 		},
 		&SettingDef{
-			Name:    "Number of violations",
+			Name:    "Number of violations (sum of weights)",
 			Key:     "Treshold",
 			Kind:    SettingTypeInt,
 			Default: 4,
@@ -392,7 +403,7 @@ func (vt *ViolationsTrigger) CheckUser(ctxData *TriggeredRuleData, violations []
 			continue
 		}
 
-		numRecent++
+		numRecent += violationWeightAt(v, time.Now())
 	}
 
 	if numRecent >= settingsCast.Treshold {
@@ -402,6 +413,27 @@ func (vt *ViolationsTrigger) CheckUser(ctxData *TriggeredRuleData, violations []
 	return false, nil
 }
 
+// violationWeightAt returns the weight left of the violation at t, violations that decay
+// lose their weight linearly from when they were created up until decays_at
+func violationWeightAt(v *models.AutomodViolation, t time.Time) int {
+	if !v.DecaysAt.Valid {
+		return v.Weight
+	}
+
+	total := v.DecaysAt.Time.Sub(v.CreatedAt)
+	elapsed := t.Sub(v.CreatedAt)
+	if total <= 0 || elapsed >= total {
+		return 0
+	}
+
+	if elapsed <= 0 {
+		return v.Weight
+	}
+
+	lost := int(float64(v.Weight) * float64(elapsed) / float64(total))
+	return v.Weight - lost
+}
+
 /////////////////////////////////////////////////////////////
 
 type AllCapsTriggerData struct {
@@ -488,6 +520,167 @@ func (caps *AllCapsTrigger) MergeDuplicates(data []interface{}) interface{} {
 
 /////////////////////////////////////////////////////////////
 
+type EmojiFloodTriggerData struct {
+	Treshold        int
+	Percentage      int
+	CountShortcodes bool
+}
+
+var _ MessageTrigger = (*EmojiFloodTrigger)(nil)
+
+// EmojiFloodTrigger only counts emojis in the message content, the discordgo version used has no support for message stickers
+type EmojiFloodTrigger struct{}
+
+func (ef *EmojiFloodTrigger) Kind() RulePartType {
+	return RulePartTrigger
+}
+
+func (ef *EmojiFloodTrigger) DataType() interface{} {
+	return &EmojiFloodTriggerData{}
+}
+
+func (ef *EmojiFloodTrigger) Name() string {
+	return "x emojis"
+}
+
+func (ef *EmojiFloodTrigger) Description() string {
+	return "Triggers when a message contains x or more emojis (both unicode and custom ones), optionally only if they make up more than y% of the message. Stickers are not counted."
+}
+
+func (ef *EmojiFloodTrigger) UserSettings() []*SettingDef {
+	return []*SettingDef{
+		&SettingDef{
+			Name:    "Emojis",
+			Key:     "Treshold",
+			Kind:    SettingTypeInt,
+			Default: 10,
+			Min:     1,
+			Max:     2000,
+		},
+		&SettingDef{
+			Name:    "Percentage of message (0 to ignore)",
+			Key:     "Percentage",
+			Kind:    SettingTypeInt,
+			Default: 0,
+			Min:     0,
+			Max:     100,
+		},
+		&SettingDef{
+			Name: "Count emoji shortcodes such as :x:",
+			Key:  "CountShortcodes",
+			Kind: SettingTypeBool,
+		},
+	}
+}
+
+func (ef *EmojiFloodTrigger) CheckMessage(ms *dstate.MemberState, cs *dstate.ChannelState, m *discordgo.Message, mdStripped string, data interface{}) (bool, error) {
+	dataCast := data.(*EmojiFloodTriggerData)
+
+	numEmojis, numOther := countEmojis(m.Content, dataCast.CountShortcodes)
+	if numEmojis < dataCast.Treshold || numEmojis < 1 {
+		return false, nil
+	}
+
+	percentage := (numEmojis * 100) / (numEmojis + numOther)
+	if percentage >= dataCast.Percentage {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (ef *EmojiFloodTrigger) MergeDuplicates(data []interface{}) interface{} {
+	// use the strictest settings of them all
+	merged := *(data[0].(*EmojiFloodTriggerData))
+	for _, v := range data[1:] {
+		cast := v.(*EmojiFloodTriggerData)
+		if cast.Treshold < merged.Treshold {
+			merged.Treshold = cast.Treshold
+		}
+
+		if cast.Percentage < merged.Percentage {
+			merged.Percentage = cast.Percentage
+		}
+
+		merged.CountShortcodes = merged.CountShortcodes || cast.CountShortcodes
+	}
+
+	return &merged
+}
+
+var (
+	customEmojiRegex    = regexp.MustCompile(`<a?:[\w~]+:\d+>`)
+	emojiShortcodeRegex = regexp.MustCompile(`:[\w+-]+:`)
+)
+
+// countEmojis returns the number of emojis in the message, and the number of other non whitespace characters.
+// Emojis made up of multiple codepoints (flags, skin tones, zwj sequences and so on) are counted as one.
+func countEmojis(content string, countShortcodes bool) (emojis int, other int) {
+	emojis += len(customEmojiRegex.FindAllStringIndex(content, -1))
+	content = customEmojiRegex.ReplaceAllString(content, " ")
+
+	if countShortcodes {
+		emojis += len(emojiShortcodeRegex.FindAllStringIndex(content, -1))
+		content = emojiShortcodeRegex.ReplaceAllString(content, " ")
+	}
+
+	joinNext := false
+	pendingRegional := false
+	for _, r := range content {
+		switch {
+		case r == 0x200d:
+			// zero width joiner, the next emoji is part of the current one
+			joinNext = true
+		case r == 0x20e3:
+			// combining keycap, turns the previous character (a digit, # or *) into an emoji
+			if other > 0 {
+				other--
+			}
+			emojis++
+			joinNext = false
+			pendingRegional = false
+		case isEmojiModifier(r):
+			// part of the previous emoji
+		case r >= 0x1f1e6 && r <= 0x1f1ff:
+			// flags are made of pairs of regional indicators
+			if !pendingRegional {
+				emojis++
+			}
+			pendingRegional = !pendingRegional
+			joinNext = false
+		case isEmojiRune(r):
+			if !joinNext {
+				emojis++
+			}
+			joinNext = false
+			pendingRegional = false
+		default:
+			joinNext = false
+			pendingRegional = false
+			if !unicode.IsSpace(r) {
+				other++
+			}
+		}
+	}
+
+	return
+}
+
+func isEmojiModifier(r rune) bool {
+	return (r >= 0xfe00 && r <= 0xfe0f) || // variation selectors
+		(r >= 0x1f3fb && r <= 0x1f3ff) || // skin tones
+		(r >= 0xe0020 && r <= 0xe007f) // tags
+}
+
+func isEmojiRune(r rune) bool {
+	return (r >= 0x1f000 && r <= 0x1faff) ||
+		(r >= 0x2600 && r <= 0x27bf) ||
+		(r >= 0x2300 && r <= 0x23ff) ||
+		(r >= 0x2b00 && r <= 0x2bff)
+}
+
+/////////////////////////////////////////////////////////////
+
 var _ MessageTrigger = (*ServerInviteTrigger)(nil)
 
 type ServerInviteTrigger struct{}
@@ -1018,7 +1211,8 @@ type NicknameWordlistTrigger struct {
 	Blacklist bool
 }
 type NicknameWordlistTriggerData struct {
-	ListID int64
+	ListID    int64
+	Normalize bool
 }
 
 func (nwl *NicknameWordlistTrigger) Kind() RulePartType {
@@ -1052,6 +1246,11 @@ func (nwl *NicknameWordlistTrigger) UserSettings() []*SettingDef {
 			Key:  "ListID",
 			Kind: SettingTypeList,
 		},
+		&SettingDef{
+			Name: "Normalize lookalike characters (e.g homoglyphs, fullwidth and zero width characters)",
+			Key:  "Normalize",
+			Kind: SettingTypeBool,
+		},
 	}
 }
 
@@ -1063,11 +1262,18 @@ func (nwl *NicknameWordlistTrigger) CheckNickname(ms *dstate.MemberState, data i
 		return false, nil
 	}
 
-	fields := strings.Fields(PrepareMessageForWordCheck(ms.Nick))
+	name := ms.Nick
+	words := list.Content
+	if dataCast.Normalize {
+		name = NormalizeConfusables(name)
+		words = list.NormalizedContent
+	}
+
+	fields := strings.Fields(PrepareMessageForWordCheck(name))
 
 	for _, mf := range fields {
 		contained := false
-		for _, w := range list.Content {
+		for _, w := range words {
 			if strings.EqualFold(mf, w) {
 				if nwl.Blacklist {
 					// contains a blacklisted word, trigger
@@ -1151,7 +1357,8 @@ type UsernameWordlistTrigger struct {
 	Blacklist bool
 }
 type UsernameWorldlistData struct {
-	ListID int64
+	ListID    int64
+	Normalize bool
 }
 
 func (uwl *UsernameWordlistTrigger) Kind() RulePartType {
@@ -1185,6 +1392,11 @@ func (uwl *UsernameWordlistTrigger) UserSettings() []*SettingDef {
 			Key:  "ListID",
 			Kind: SettingTypeList,
 		},
+		&SettingDef{
+			Name: "Normalize lookalike characters (e.g homoglyphs, fullwidth and zero width characters)",
+			Key:  "Normalize",
+			Kind: SettingTypeBool,
+		},
 	}
 }
 
@@ -1196,11 +1408,18 @@ func (uwl *UsernameWordlistTrigger) CheckUsername(ms *dstate.MemberState, data i
 		return false, nil
 	}
 
-	fields := strings.Fields(PrepareMessageForWordCheck(ms.Username))
+	name := ms.Username
+	words := list.Content
+	if dataCast.Normalize {
+		name = NormalizeConfusables(name)
+		words = list.NormalizedContent
+	}
+
+	fields := strings.Fields(PrepareMessageForWordCheck(name))
 
 	for _, mf := range fields {
 		contained := false
-		for _, w := range list.Content {
+		for _, w := range words {
 			if strings.EqualFold(mf, w) {
 				if uwl.Blacklist {
 					// contains a blacklisted word, trigger
@@ -1289,6 +1508,80 @@ func (mj *MemberJoinTrigger) CheckJoin(ms *dstate.MemberState, data interface{})
 
 /////////////////////////////////////////////////////////////
 
+type JoinRaidTriggerData struct {
+	Treshold      int
+	Interval      int
+	MaxAccountAge int
+}
+
+var _ JoinListener = (*JoinRaidTrigger)(nil)
+
+type JoinRaidTrigger struct{}
+
+func (jr *JoinRaidTrigger) Kind() RulePartType {
+	return RulePartTrigger
+}
+
+func (jr *JoinRaidTrigger) DataType() interface{} {
+	return &JoinRaidTriggerData{}
+}
+
+func (jr *JoinRaidTrigger) Name() string {
+	return "x joins in y seconds"
+}
+
+func (jr *JoinRaidTrigger) Description() string {
+	return "Triggers when x or more members joined the server within y seconds (including the member joining), optionally only counting new accounts. Triggers for every member that joins while this is the case."
+}
+
+func (jr *JoinRaidTrigger) UserSettings() []*SettingDef {
+	return []*SettingDef{
+		&SettingDef{
+			Name:    "Joins",
+			Key:     "Treshold",
+			Kind:    SettingTypeInt,
+			Default: 10,
+			Min:     2,
+			Max:     maxTrackedJoins,
+		},
+		&SettingDef{
+			Name:    "Within (seconds)",
+			Key:     "Interval",
+			Kind:    SettingTypeInt,
+			Default: 30,
+			Min:     1,
+			Max:     int(maxTrackedJoinAge / time.Second),
+		},
+		&SettingDef{
+			Name:    "Only count accounts younger than (minutes, 0 to count all)",
+			Key:     "MaxAccountAge",
+			Kind:    SettingTypeInt,
+			Default: 0,
+			Min:     0,
+			Max:     525600,
+		},
+	}
+}
+
+func (jr *JoinRaidTrigger) CheckJoin(ms *dstate.MemberState, data interface{}) (isAffected bool, err error) {
+	dataCast := data.(*JoinRaidTriggerData)
+
+	within := time.Duration(dataCast.Interval) * time.Second
+	maxAccountAge := time.Duration(dataCast.MaxAccountAge) * time.Minute
+
+	if recentJoins.count(ms.Guild.ID, within, maxAccountAge) >= dataCast.Treshold {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (jr *JoinRaidTrigger) MergeDuplicates(data []interface{}) interface{} {
+	return data[0] // no point in having duplicates of this
+}
+
+/////////////////////////////////////////////////////////////
+
 var _ MessageTrigger = (*MessageAttachmentTrigger)(nil)
 
 type MessageAttachmentTrigger struct {
@@ -1337,3 +1630,193 @@ func (mat *MessageAttachmentTrigger) CheckMessage(ms *dstate.MemberState, cs *ds
 func (mat *MessageAttachmentTrigger) MergeDuplicates(data []interface{}) interface{} {
 	return data[0] // no point in having duplicates of this
 }
+
+/////////////////////////////////////////////////////////////
+
+type DuplicateMessageTriggerData struct {
+	Treshold int
+	Interval int
+}
+
+var _ MessageTrigger = (*DuplicateMessageTrigger)(nil)
+
+type DuplicateMessageTrigger struct {
+	CrossChannel bool
+}
+
+func (dm *DuplicateMessageTrigger) Kind() RulePartType {
+	return RulePartTrigger
+}
+
+func (dm *DuplicateMessageTrigger) DataType() interface{} {
+	return &DuplicateMessageTriggerData{}
+}
+
+func (dm *DuplicateMessageTrigger) Name() string {
+	if dm.CrossChannel {
+		return "x duplicate messages across channels in y seconds"
+	}
+
+	return "x duplicate messages in y seconds"
+}
+
+func (dm *DuplicateMessageTrigger) Description() string {
+	if dm.CrossChannel {
+		return "Triggers when a user posts the same message x times within y seconds, in any channel. Formatting, punctuation and casing is ignored when comparing messages."
+	}
+
+	return "Triggers when a user posts the same message x times within y seconds in a single channel, unlike the consecutive identical messages trigger other messages can be in between. Formatting, punctuation and casing is ignored when comparing messages."
+}
+
+func (dm *DuplicateMessageTrigger) UserSettings() []*SettingDef {
+	return []*SettingDef{
+		&SettingDef{
+			Name:    "Messages",
+			Key:     "Treshold",
+			Kind:    SettingTypeInt,
+			Min:     2,
+			Max:     250,
+			Default: 3,
+		},
+		&SettingDef{
+			Name:    "Within (seconds)",
+			Key:     "Interval",
+			Kind:    SettingTypeInt,
+			Min:     1,
+			Max:     3600,
+			Default: 60,
+		},
+	}
+}
+
+func (dm *DuplicateMessageTrigger) CheckMessage(ms *dstate.MemberState, cs *dstate.ChannelState, m *discordgo.Message, mdStripped string, data interface{}) (bool, error) {
+	settings := data.(*DuplicateMessageTriggerData)
+
+	content := normalizeDuplicateContent(m.Content)
+	if content == "" {
+		// nothing to compare, e.g attachment only messages
+		return false, nil
+	}
+
+	within := time.Duration(settings.Interval) * time.Second
+	now := time.Now()
+
+	count := 1
+
+	countInChannel := func(channel *dstate.ChannelState) {
+		// New messages are at the end
+		for i := len(channel.Messages) - 1; i >= 0; i-- {
+			cMsg := channel.Messages[i]
+
+			age := now.Sub(cMsg.ParsedCreated)
+			if age > within {
+				break
+			}
+
+			if cMsg.ID == m.ID || cMsg.Author.ID != ms.ID {
+				continue
+			}
+
+			if normalizeDuplicateContent(cMsg.Content) == content {
+				count++
+			}
+		}
+	}
+
+	cs.Owner.RLock()
+	defer cs.Owner.RUnlock()
+
+	if dm.CrossChannel {
+		for _, channel := range cs.Guild.Channels {
+			countInChannel(channel)
+			if count >= settings.Treshold {
+				return true, nil
+			}
+		}
+	} else {
+		countInChannel(cs)
+	}
+
+	if count >= settings.Treshold {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (dm *DuplicateMessageTrigger) MergeDuplicates(data []interface{}) interface{} {
+	return data[0] // no point in having duplicates of this
+}
+
+// normalizeDuplicateContent strips markdown and other punctuation, lowercases and collapses whitespace in a message
+// so that messages that only differ in formatting are treated as the same
+func normalizeDuplicateContent(content string) string {
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+
+		return unicode.ToLower(r)
+	}, content)
+
+	normalized := strings.Join(strings.Fields(stripped), " ")
+	if normalized == "" {
+		// message consisting only of symbols, e.g emojis
+		return strings.TrimSpace(strings.ToLower(content))
+	}
+
+	return normalized
+}
+
+/////////////////////////////////////////////////////////////
+
+type ZalgoTriggerData struct {
+	Treshold int
+}
+
+var _ MessageTrigger = (*ZalgoTrigger)(nil)
+
+type ZalgoTrigger struct{}
+
+func (z *ZalgoTrigger) Kind() RulePartType {
+	return RulePartTrigger
+}
+
+func (z *ZalgoTrigger) DataType() interface{} {
+	return &ZalgoTriggerData{}
+}
+
+func (z *ZalgoTrigger) Name() string {
+	return "Zalgo text"
+}
+
+func (z *ZalgoTrigger) Description() string {
+	return "Triggers when a message has a character with x or more combining characters stacked on top of it (zalgo text)"
+}
+
+func (z *ZalgoTrigger) UserSettings() []*SettingDef {
+	return []*SettingDef{
+		&SettingDef{
+			Name:    "Combining characters on a single character",
+			Key:     "Treshold",
+			Kind:    SettingTypeInt,
+			Default: 4,
+			Min:     1,
+			Max:     1000,
+		},
+	}
+}
+
+func (z *ZalgoTrigger) CheckMessage(ms *dstate.MemberState, cs *dstate.ChannelState, m *discordgo.Message, mdStripped string, data interface{}) (bool, error) {
+	dataCast := data.(*ZalgoTriggerData)
+
+	if MaxStackedCombiningMarks(m.Content) >= dataCast.Treshold {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (z *ZalgoTrigger) MergeDuplicates(data []interface{}) interface{} {
+	return data[0] // no point in having duplicates of this
+}
@@ -19,6 +19,7 @@ type DeleteMessagesEvent struct {
 func init() {
 	RegisterHandler("delete_messages", DeleteMessagesEvent{}, handleDeleteMessagesEvent)
 	RegisterHandler("std_remove_member_role", RmoveRoleData{}, handleRemoveMemberRole)
+	RegisterHandler("std_add_member_role", AddRoleData{}, handleAddMemberRole)
 }
 
 func ScheduleDeleteMessages(guildID, channelID int64, when time.Time, messages ...int64) error {
@@ -95,3 +96,37 @@ func handleRemoveMemberRole(evt *models.ScheduledEvent, data interface{}) (retry
 
 	return CheckDiscordErrRetry(err), err
 }
+
+type AddRoleData struct {
+	GuildID int64 `json:"guild_id"`
+	UserID  int64 `json:"user_id"`
+	RoleID  int64 `json:"role_id"`
+}
+
+// ScheduleAddRole schedules a role to be given back to a member, used for temporarily removing roles
+func ScheduleAddRole(ctx context.Context, guildID, userID, roleID int64, when time.Time) error {
+	// remove existing role add events for this role, same as with ScheduleRemoveRole
+	_, err := models.ScheduledEvents(qm.Where("event_name='std_add_member_role' AND  guild_id = ? AND (data->>'user_id')::bigint = ? AND (data->>'role_id')::bigint = ? AND processed = false", guildID, userID, roleID)).DeleteAll(ctx, common.PQ)
+	if err != nil {
+		return err
+	}
+
+	// add the scheduled event for it
+	err = ScheduleEvent("std_add_member_role", guildID, when, &AddRoleData{
+		GuildID: guildID,
+		UserID:  userID,
+		RoleID:  roleID,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func handleAddMemberRole(evt *models.ScheduledEvent, data interface{}) (retry bool, err error) {
+	dataCast := data.(*AddRoleData)
+	err = common.BotSession.GuildMemberRoleAdd(dataCast.GuildID, dataCast.UserID, dataCast.RoleID)
+	return CheckDiscordErrRetry(err), err
+}
@@ -55,6 +55,8 @@ func (p *Plugin) AddCommands() {
 
 func (p *Plugin) BotInit() {
 	eventsystem.AddHandlerAsyncLast(bot.ConcurrentEventHandler(HandleMessageCreate), eventsystem.EventMessageCreate)
+	eventsystem.AddHandlerAsyncLast(bot.ConcurrentEventHandler(HandleMessageReactionAddRemove), eventsystem.EventMessageReactionAdd, eventsystem.EventMessageReactionRemove)
+	eventsystem.AddHandlerAsyncLast(bot.ConcurrentEventHandler(HandleGuildMemberAddRemove), eventsystem.EventGuildMemberAdd, eventsystem.EventGuildMemberRemove)
 
 	// add the pubsub handler for cache eviction
 	pubsub.AddHandler("custom_commands_clear_cache", func(event *pubsub.Event) {
@@ -297,7 +299,7 @@ func HandleMessageCreate(evt *eventsystem.EventData) {
 		return
 	}
 
-	cmds, err := BotCachedGetCommandsWithMessageTriggers(cs.Guild, evt.Context())
+	cmds, err := BotCachedGetCommandsWithTriggers(cs.Guild, evt.Context())
 	if err != nil {
 		logger.WithError(err).WithField("guild", cs.Guild.ID).Error("Failed retrieving comamnds")
 		return
@@ -322,7 +324,7 @@ func HandleMessageCreate(evt *eventsystem.EventData) {
 	var stripped string
 	var args []string
 	for _, cmd := range cmds {
-		if !CmdRunsInChannel(cmd, mc.ChannelID) || !CmdRunsForUser(cmd, member) {
+		if !isMessageTrigger(cmd) || !CmdRunsInChannel(cmd, mc.ChannelID) || !CmdRunsForUser(cmd, member) {
 			continue
 		}
 		if matched != nil && cmd.TriggerType == int(CommandTriggerRegex) {
@@ -381,8 +383,190 @@ func ExecuteCustomCommandFromMessage(cmd *models.CustomCommand, member *dstate.M
 	return ExecuteCustomCommand(cmd, tmplCtx)
 }
 
+func HandleMessageReactionAddRemove(evt *eventsystem.EventData) {
+	var reaction *discordgo.MessageReaction
+	added := false
+	switch e := evt.EvtInterface.(type) {
+	case *discordgo.MessageReactionAdd:
+		reaction = e.MessageReaction
+		added = true
+	case *discordgo.MessageReactionRemove:
+		reaction = e.MessageReaction
+	}
+
+	if reaction.GuildID == 0 || reaction.UserID == common.BotUser.ID {
+		return
+	}
+
+	cs := bot.State.Channel(true, reaction.ChannelID)
+	if cs == nil || cs.IsPrivate || !bot.BotProbablyHasPermissionGS(true, cs.Guild, cs.ID, discordgo.PermissionSendMessages) {
+		return
+	}
+
+	cmds, err := BotCachedGetCommandsWithTriggers(cs.Guild, evt.Context())
+	if err != nil {
+		logger.WithError(err).WithField("guild", cs.Guild.ID).Error("Failed retrieving comamnds")
+		return
+	}
+
+	member, err := bot.GetMember(cs.Guild.ID, reaction.UserID)
+	if err != nil || member == nil || member.Bot {
+		return
+	}
+
+	var matched *models.CustomCommand
+	for _, cmd := range cmds {
+		if !CmdRunsInChannel(cmd, cs.ID) || !CmdRunsForUser(cmd, member) || !ReactionTriggerMatches(cmd, reaction, added) {
+			continue
+		}
+
+		matched = cmd
+
+		// triggers filtered to a specific message has priority over the ones running on all messages
+		if cmd.ReactionTriggerMessage != 0 {
+			break
+		}
+	}
+
+	if matched == nil || len(matched.Responses) == 0 {
+		return
+	}
+
+	message, err := common.BotSession.ChannelMessage(cs.ID, reaction.MessageID)
+	if err != nil {
+		return
+	}
+
+	if common.Statsd != nil {
+		go common.Statsd.Incr("yagpdb.cc.executed", nil, 1)
+	}
+
+	err = ExecuteCustomCommandFromReaction(matched, member, cs, reaction, added, message)
+	if err != nil {
+		logger.WithField("guild", cs.Guild.ID).WithError(err).Error("Error executing custom command")
+	}
+}
+
+// ReactionTriggerMatches returns true if the reaction passes the add/remove, message and emoji filters of a reaction trigger
+func ReactionTriggerMatches(cmd *models.CustomCommand, reaction *discordgo.MessageReaction, added bool) bool {
+	if cmd.TriggerType != int(CommandTriggerReaction) {
+		return false
+	}
+
+	if (added && cmd.ReactionTriggerMode == ReactionModeRemoveOnly) || (!added && cmd.ReactionTriggerMode == ReactionModeAddOnly) {
+		return false
+	}
+
+	if cmd.ReactionTriggerMessage != 0 && cmd.ReactionTriggerMessage != reaction.MessageID {
+		return false
+	}
+
+	emojis := strings.Fields(cmd.TextTrigger)
+	if len(emojis) < 1 {
+		// no emoji filter
+		return true
+	}
+
+	for _, v := range emojis {
+		if v == reaction.Emoji.Name {
+			return true
+		}
+
+		// custom emojis can be specified by their id or in the <:name:id> format
+		if reaction.Emoji.ID != 0 && parseReactionEmojiID(v) == reaction.Emoji.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseReactionEmojiID returns the id of a custom emoji in the <:name:id> or <a:name:id> format, or a bare id. Returns 0 if it's neither
+func parseReactionEmojiID(s string) int64 {
+	if strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">") {
+		s = s[1 : len(s)-1]
+		if i := strings.LastIndex(s, ":"); i != -1 {
+			s = s[i+1:]
+		}
+	}
+
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
+
+func ExecuteCustomCommandFromReaction(cmd *models.CustomCommand, member *dstate.MemberState, cs *dstate.ChannelState, reaction *discordgo.MessageReaction, added bool, message *discordgo.Message) error {
+	tmplCtx := templates.NewContext(cs.Guild, cs, member)
+
+	tmplCtx.Data["Reaction"] = reaction
+	tmplCtx.Data["ReactionMessage"] = message
+	tmplCtx.Data["ReactionAdded"] = added
+
+	return ExecuteCustomCommand(cmd, tmplCtx)
+}
+
+// HandleGuildMemberAddRemove runs the join and leave triggered commands in their context channels
+func HandleGuildMemberAddRemove(evt *eventsystem.EventData) {
+	var member *discordgo.Member
+	triggerType := CommandTriggerJoin
+	switch e := evt.EvtInterface.(type) {
+	case *discordgo.GuildMemberAdd:
+		member = e.Member
+	case *discordgo.GuildMemberRemove:
+		member = e.Member
+		triggerType = CommandTriggerLeave
+	}
+
+	if member.User == nil || member.User.ID == common.BotUser.ID {
+		return
+	}
+
+	gs := bot.State.Guild(true, member.GuildID)
+	if gs == nil {
+		return
+	}
+
+	cmds, err := BotCachedGetCommandsWithTriggers(gs, evt.Context())
+	if err != nil {
+		logger.WithError(err).WithField("guild", gs.ID).Error("Failed retrieving comamnds")
+		return
+	}
+
+	ms := dstate.MSFromDGoMember(gs, member)
+
+	for _, cmd := range cmds {
+		if cmd.TriggerType != int(triggerType) || len(cmd.Responses) == 0 {
+			continue
+		}
+
+		// on leave the member is already removed from the state and has no roles, so role restrictions
+		// can't be checked, which is also why leave triggered commands can't be saved with them
+		if triggerType != CommandTriggerLeave && !CmdRunsForUser(cmd, ms) {
+			continue
+		}
+
+		cs := gs.Channel(true, cmd.ContextChannel)
+		if cs == nil || !CmdRunsInChannel(cmd, cs.ID) || !bot.BotProbablyHasPermissionGS(true, gs, cs.ID, discordgo.PermissionSendMessages) {
+			continue
+		}
+
+		if common.Statsd != nil {
+			go common.Statsd.Incr("yagpdb.cc.executed", nil, 1)
+		}
+
+		err = ExecuteCustomCommand(cmd, templates.NewContext(gs, cs, ms))
+		if err != nil {
+			logger.WithField("guild", gs.ID).WithError(err).Error("Error executing custom command")
+		}
+	}
+}
+
 // func ExecuteCustomCommand(cmd *models.CustomCommand, cmdArgs []string, stripped string, s *discordgo.Session, m *discordgo.MessageCreate) (resp string, tmplCtx *templates.Context, err error) {
 func ExecuteCustomCommand(cmd *models.CustomCommand, tmplCtx *templates.Context) error {
+	started := time.Now()
 	defer func() {
 		if err := recover(); err != nil {
 			actualErr := ""
@@ -393,6 +577,7 @@ func ExecuteCustomCommand(cmd *models.CustomCommand, tmplCtx *templates.Context)
 				actualErr = t
 			}
 			onExecError(errors.New(actualErr), tmplCtx, true)
+			recordExecution(cmd, tmplCtx, started, errors.New(actualErr))
 		}
 	}()
 
@@ -416,11 +601,15 @@ func ExecuteCustomCommand(cmd *models.CustomCommand, tmplCtx *templates.Context)
 	if lockHandle == -1 {
 		f.Warn("Exceeded max lock attempts for cc")
 		common.BotSession.ChannelMessageSend(tmplCtx.CS.ID, fmt.Sprintf("Gave up trying to execute custom command #%d after 1 minute because there is already one or more instances of it being executed.", cmd.LocalID))
+		recordExecution(cmd, tmplCtx, started, errors.New("gave up waiting for other executions of the command to finish"))
 		return nil
 	}
 
 	defer CCExecLock.Unlock(lockKey, lockHandle)
 
+	// don't include the time spent waiting for the lock in the exec log
+	started = time.Now()
+
 	// pick a response and execute it
 	f.Info("Custom command triggered")
 
@@ -438,6 +627,8 @@ func ExecuteCustomCommand(cmd *models.CustomCommand, tmplCtx *templates.Context)
 		out += "`" + common.EscapeSpecialMentions(err.Error()) + "`"
 	}
 
+	recordExecution(cmd, tmplCtx, started, err)
+
 	for _, v := range tmplCtx.EmebdsToSend {
 		common.BotSession.ChannelMessageSendEmbed(tmplCtx.CS.ID, v)
 	}
@@ -550,7 +741,18 @@ const (
 	CacheKeyDBLimits
 )
 
-func BotCachedGetCommandsWithMessageTriggers(gs *dstate.GuildState, ctx context.Context) ([]*models.CustomCommand, error) {
+// isMessageTrigger returns true if the command is triggered by messages, and not reactions and such
+func isMessageTrigger(cmd *models.CustomCommand) bool {
+	switch CommandTriggerType(cmd.TriggerType) {
+	case CommandTriggerCommand, CommandTriggerStartsWith, CommandTriggerContains, CommandTriggerRegex, CommandTriggerExact:
+		return true
+	}
+
+	return false
+}
+
+// BotCachedGetCommandsWithTriggers returns all the commands that are triggered by events, that is everything except interval commands
+func BotCachedGetCommandsWithTriggers(gs *dstate.GuildState, ctx context.Context) ([]*models.CustomCommand, error) {
 	v, err := gs.UserCacheFetch(true, CacheKeyCommands, func() (interface{}, error) {
 		return models.CustomCommands(qm.Where("guild_id = ? AND trigger_type != 5", gs.Guild.ID), qm.OrderBy("local_id desc"), qm.Load("Group")).AllG(ctx)
 	})
@@ -95,3 +95,82 @@ func TestCheckMatch(t *testing.T) {
 		}
 	}
 }
+
+func TestReactionTriggerMatches(t *testing.T) {
+	tests := []struct {
+		// Have
+		cmd      *models.CustomCommand
+		reaction *discordgo.MessageReaction
+		added    bool
+		// Want
+		match bool
+	}{
+		{
+			&models.CustomCommand{TriggerType: int(CommandTriggerReaction)},
+			&discordgo.MessageReaction{MessageID: 1, Emoji: discordgo.Emoji{Name: "👍"}},
+			true,
+			true,
+		},
+		{
+			&models.CustomCommand{TriggerType: int(CommandTriggerReaction), ReactionTriggerMode: ReactionModeAddOnly},
+			&discordgo.MessageReaction{MessageID: 1, Emoji: discordgo.Emoji{Name: "👍"}},
+			false,
+			false,
+		},
+		{
+			&models.CustomCommand{TriggerType: int(CommandTriggerReaction), ReactionTriggerMode: ReactionModeRemoveOnly},
+			&discordgo.MessageReaction{MessageID: 1, Emoji: discordgo.Emoji{Name: "👍"}},
+			false,
+			true,
+		},
+		{
+			&models.CustomCommand{TriggerType: int(CommandTriggerReaction), ReactionTriggerMessage: 2},
+			&discordgo.MessageReaction{MessageID: 1, Emoji: discordgo.Emoji{Name: "👍"}},
+			true,
+			false,
+		},
+		{
+			&models.CustomCommand{TriggerType: int(CommandTriggerReaction), TextTrigger: "👎 ⭐"},
+			&discordgo.MessageReaction{MessageID: 1, Emoji: discordgo.Emoji{Name: "⭐"}},
+			true,
+			true,
+		},
+		{
+			&models.CustomCommand{TriggerType: int(CommandTriggerReaction), TextTrigger: "👎 ⭐"},
+			&discordgo.MessageReaction{MessageID: 1, Emoji: discordgo.Emoji{Name: "👍"}},
+			true,
+			false,
+		},
+		{
+			&models.CustomCommand{TriggerType: int(CommandTriggerReaction), TextTrigger: "<:yes:1234>"},
+			&discordgo.MessageReaction{MessageID: 1, Emoji: discordgo.Emoji{Name: "yes", ID: 1234}},
+			true,
+			true,
+		},
+		{
+			&models.CustomCommand{TriggerType: int(CommandTriggerReaction), TextTrigger: "1234"},
+			&discordgo.MessageReaction{MessageID: 1, Emoji: discordgo.Emoji{Name: "yes", ID: 1234}},
+			true,
+			true,
+		},
+		{
+			&models.CustomCommand{TriggerType: int(CommandTriggerReaction), TextTrigger: "<:no:12345>"},
+			&discordgo.MessageReaction{MessageID: 1, Emoji: discordgo.Emoji{Name: "yes", ID: 1234}},
+			true,
+			false,
+		},
+		{
+			&models.CustomCommand{TriggerType: int(CommandTriggerCommand)},
+			&discordgo.MessageReaction{MessageID: 1, Emoji: discordgo.Emoji{Name: "👍"}},
+			true,
+			false,
+		},
+	}
+
+	for i, test := range tests {
+		m := ReactionTriggerMatches(test.cmd, test.reaction, test.added)
+		if m != test.match {
+			t.Errorf("%d: got match '%t', want match '%t'", i, m, test.match)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/jonas747/discordgo"
 	"github.com/jonas747/dstate"
@@ -54,6 +55,16 @@ const (
 	CommandTriggerExact      CommandTriggerType = 4
 
 	CommandTriggerInterval CommandTriggerType = 5
+	CommandTriggerReaction CommandTriggerType = 6
+	CommandTriggerJoin     CommandTriggerType = 7
+	CommandTriggerLeave    CommandTriggerType = 8
+)
+
+// What reaction events a reaction trigger runs on
+const (
+	ReactionModeBoth       = 0
+	ReactionModeAddOnly    = 1
+	ReactionModeRemoveOnly = 2
 )
 
 var (
@@ -64,6 +75,9 @@ var (
 		CommandTriggerRegex,
 		CommandTriggerExact,
 		CommandTriggerInterval,
+		CommandTriggerReaction,
+		CommandTriggerJoin,
+		CommandTriggerLeave,
 	}
 
 	triggerStrings = map[CommandTriggerType]string{
@@ -73,6 +87,9 @@ var (
 		CommandTriggerRegex:      "Regex",
 		CommandTriggerExact:      "Exact",
 		CommandTriggerInterval:   "Interval",
+		CommandTriggerReaction:   "Reaction",
+		CommandTriggerJoin:       "Join",
+		CommandTriggerLeave:      "Leave",
 	}
 )
 
@@ -96,6 +113,15 @@ type CustomCommand struct {
 	TimeTriggerExcludingDays  []int64 `schema:"time_trigger_excluding_days"`
 	TimeTriggerExcludingHours []int64 `schema:"time_trigger_excluding_hours"`
 
+	// Used instead of the interval if set, the timezone defaults to UTC
+	TimeTriggerCron     string `schema:"time_trigger_cron" valid:",0,100"`
+	TimeTriggerTimezone string `schema:"time_trigger_timezone" valid:",0,100"`
+
+	// Space separated list of emojis, if empty all emojis will trigger it
+	ReactionTriggerEmoji   string `schema:"reaction_trigger_emoji" valid:",0,1000"`
+	ReactionTriggerMode    int    `schema:"reaction_trigger_mode"`
+	ReactionTriggerMessage int64  `schema:"reaction_trigger_message"`
+
 	// If set, then the following channels are required, otherwise they are ignored
 	RequireChannels bool    `json:"require_channels" schema:"require_channels"`
 	Channels        []int64 `json:"channels" schema:"channels"`
@@ -137,6 +163,44 @@ func (cc *CustomCommand) Validate(tmpl web.TemplateData) (ok bool) {
 		return false
 	}
 
+	if cc.TriggerTypeForm == "reaction" && (cc.ReactionTriggerMode < ReactionModeBoth || cc.ReactionTriggerMode > ReactionModeRemoveOnly) {
+		tmpl.AddAlerts(web.ErrorAlert("Invalid reaction trigger mode"))
+		return false
+	}
+
+	// join and leave triggered commands run in the context channel, so they never run without one
+	if (cc.TriggerTypeForm == "join" || cc.TriggerTypeForm == "leave") && cc.ContextChannel == 0 {
+		tmpl.AddAlerts(web.ErrorAlert("Join and leave triggered commands need a channel to run in"))
+		return false
+	}
+
+	// discord does not send the roles of members that left, so role restrictions could never be checked
+	if cc.TriggerTypeForm == "leave" && (len(cc.Roles) > 0 || cc.RequireRoles) {
+		tmpl.AddAlerts(web.ErrorAlert("Leave triggered commands can't be restricted by roles"))
+		return false
+	}
+
+	if cc.TriggerTypeForm == "interval_cron" {
+		schedule, loc, err := ParseCronSchedule(strings.TrimSpace(cc.TimeTriggerCron), strings.TrimSpace(cc.TimeTriggerTimezone))
+		if err != nil {
+			tmpl.AddAlerts(web.ErrorAlert("Invalid cron expression or timezone: ", err.Error()))
+			return false
+		}
+
+		// Next returns the zero time for schedules with no run within the next 5 years, like the 30th of february
+		now := time.Now()
+		if schedule.Next(now.In(loc)).IsZero() {
+			tmpl.AddAlerts(web.ErrorAlert("The cron schedule never runs"))
+			return false
+		}
+
+		// 0 means it runs at most once in the next year, which is fine
+		if interval := CronMinInterval(schedule, loc, now); interval != 0 && interval < time.Minute {
+			tmpl.AddAlerts(web.ErrorAlert("Cron schedules can run at most once per minute"))
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -156,6 +220,9 @@ func (cc *CustomCommand) ToDBModel() *models.CustomCommand {
 		TimeTriggerExcludingHours: cc.TimeTriggerExcludingHours,
 		ContextChannel:            cc.ContextChannel,
 
+		ReactionTriggerMode:    cc.ReactionTriggerMode,
+		ReactionTriggerMessage: cc.ReactionTriggerMessage,
+
 		Responses: cc.Responses,
 	}
 
@@ -175,6 +242,21 @@ func (cc *CustomCommand) ToDBModel() *models.CustomCommand {
 		pqCommand.TimeTriggerInterval *= 60
 	}
 
+	if cc.TriggerTypeForm == "interval_cron" {
+		pqCommand.TimeTriggerCron = strings.TrimSpace(cc.TimeTriggerCron)
+		pqCommand.TimeTriggerTimezone = strings.TrimSpace(cc.TimeTriggerTimezone)
+
+		// store the shortest interval between runs so the low interval limits still apply
+		schedule, loc, err := ParseCronSchedule(pqCommand.TimeTriggerCron, pqCommand.TimeTriggerTimezone)
+		if err == nil {
+			pqCommand.TimeTriggerInterval = int(CronMinInterval(schedule, loc, time.Now()) / time.Minute)
+		}
+	}
+
+	if cc.TriggerTypeForm == "reaction" {
+		pqCommand.TextTrigger = strings.TrimSpace(cc.ReactionTriggerEmoji)
+	}
+
 	return pqCommand
 }
 
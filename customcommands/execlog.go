@@ -0,0 +1,133 @@
+package customcommands
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/jonas747/retryableredis"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/templates"
+	"github.com/jonas747/yagpdb/customcommands/models"
+	"github.com/mediocregopher/radix"
+)
+
+const (
+	// MaxExecLogEntries is the number of the latest executions kept per custom command
+	MaxExecLogEntries = 10
+
+	// the log of a command that hasn't been ran in this long is removed
+	execLogExpiry = time.Hour * 24 * 30
+)
+
+func KeyExecLog(guildID, ccID int64) string {
+	return "custom_commands_exec_log:" + strconv.FormatInt(guildID, 10) + ":" + strconv.FormatInt(ccID, 10)
+}
+
+// ExecLogEntry is the result of a single execution of a custom command
+type ExecLogEntry struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+
+	// Zero for commands without a user, such as interval commands
+	UserID   int64  `json:"user_id,string"`
+	Username string `json:"username"`
+
+	ChannelID   int64  `json:"channel_id,string"`
+	ChannelName string `json:"channel_name"`
+
+	Error string `json:"error,omitempty"`
+
+	// The number of calls to rate limited functions, as counted by IncreaseCheckCallCounter
+	Counters map[string]int `json:"counters,omitempty"`
+}
+
+func (e *ExecLogEntry) HumanDuration() string {
+	if e.Duration < time.Millisecond {
+		return "<1ms"
+	}
+
+	return e.Duration.Round(time.Millisecond).String()
+}
+
+// recordExecution adds the result of the execution to the exec log of the command, dropping the oldest entry if it's full
+func recordExecution(cmd *models.CustomCommand, tmplCtx *templates.Context, started time.Time, execErr error) {
+	entry := &ExecLogEntry{
+		Time:     started,
+		Duration: time.Since(started),
+		Counters: tmplCtx.Counters,
+	}
+
+	if tmplCtx.MS != nil {
+		user := tmplCtx.MS.DGoUser()
+		entry.UserID = user.ID
+		entry.Username = user.Username + "#" + user.Discriminator
+	}
+
+	if tmplCtx.CS != nil {
+		entry.ChannelID = tmplCtx.CS.ID
+		entry.ChannelName = tmplCtx.CS.Copy(true).Name
+	}
+
+	if execErr != nil {
+		entry.Error = execErr.Error()
+	}
+
+	serialized, err := json.Marshal(entry)
+	if err != nil {
+		logger.WithError(err).Error("failed marshalling custom command exec log entry")
+		return
+	}
+
+	key := KeyExecLog(cmd.GuildID, cmd.LocalID)
+	err = common.RedisPool.Do(radix.Pipeline(
+		retryableredis.Cmd(nil, "LPUSH", key, string(serialized)),
+		retryableredis.Cmd(nil, "LTRIM", key, "0", strconv.Itoa(MaxExecLogEntries-1)),
+		retryableredis.Cmd(nil, "EXPIRE", key, strconv.Itoa(int(execLogExpiry.Seconds()))),
+	))
+	if err != nil {
+		logger.WithError(err).WithField("guild", cmd.GuildID).Error("failed updating custom command exec log")
+	}
+}
+
+// GetExecLogs returns the exec logs of the commands, newest first, keyed by the local id of the command
+func GetExecLogs(guildID int64, ccIDs []int64) (map[int64][]*ExecLogEntry, error) {
+	if len(ccIDs) < 1 {
+		return map[int64][]*ExecLogEntry{}, nil
+	}
+
+	raw := make([][]string, len(ccIDs))
+	actions := make([]radix.CmdAction, len(ccIDs))
+	for i, id := range ccIDs {
+		actions[i] = retryableredis.Cmd(&raw[i], "LRANGE", KeyExecLog(guildID, id), "0", "-1")
+	}
+
+	err := common.RedisPool.Do(radix.Pipeline(actions...))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64][]*ExecLogEntry, len(ccIDs))
+	for i, entries := range raw {
+		decoded := make([]*ExecLogEntry, 0, len(entries))
+		for _, v := range entries {
+			var entry *ExecLogEntry
+			err = json.Unmarshal([]byte(v), &entry)
+			if err != nil {
+				logger.WithError(err).WithField("guild", guildID).Error("failed decoding custom command exec log entry")
+				continue
+			}
+
+			decoded = append(decoded, entry)
+		}
+
+		result[ccIDs[i]] = decoded
+	}
+
+	return result, nil
+}
+
+// DelExecLog removes the exec log of a command
+func DelExecLog(guildID, ccID int64) error {
+	return common.RedisPool.Do(retryableredis.Cmd(nil, "DEL", KeyExecLog(guildID, ccID)))
+}
@@ -9,12 +9,19 @@ import (
 	schEventsModels "github.com/jonas747/yagpdb/common/scheduledevents2/models"
 	"github.com/jonas747/yagpdb/customcommands/models"
 	"github.com/pkg/errors"
+	// pinned to v1.2.0 in the Dockerfile, ParseStandard and Next returning the zero time for schedules
+	// that don't run within 5 years are relied on
+	"github.com/robfig/cron"
 	"github.com/volatiletech/null"
 	"github.com/volatiletech/sqlboiler/boil"
 	"github.com/volatiletech/sqlboiler/queries/qm"
 )
 
 func CalcNextRunTime(cc *models.CustomCommand, now time.Time) time.Time {
+	if cc.TimeTriggerCron != "" {
+		return calcNextCronRunTime(cc, now)
+	}
+
 	if len(cc.TimeTriggerExcludingDays) >= 7 || len(cc.TimeTriggerExcludingHours) >= 24 {
 		// this can never be ran...
 		return time.Time{}
@@ -62,6 +69,54 @@ func CalcNextRunTime(cc *models.CustomCommand, now time.Time) time.Time {
 	return tNext
 }
 
+// ParseCronSchedule parses a standard cron expression (or a descriptor like @daily) and the timezone it runs in, an empty timezone means UTC
+func ParseCronSchedule(expr string, timezone string) (cron.Schedule, *time.Location, error) {
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return schedule, loc, nil
+}
+
+func calcNextCronRunTime(cc *models.CustomCommand, now time.Time) time.Time {
+	schedule, loc, err := ParseCronSchedule(cc.TimeTriggerCron, cc.TimeTriggerTimezone)
+	if err != nil {
+		// validated when saved, so this should only happen if the timezone database changed
+		logger.WithError(err).WithField("guild", cc.GuildID).Error("invalid cron schedule")
+		return time.Time{}
+	}
+
+	return schedule.Next(now.In(loc)).UTC()
+}
+
+// CronMinInterval returns the shortest time between two runs of the schedule, looking at the runs in the next year.
+// It returns 0 if the schedule does not run at least once within the next year and once after that.
+func CronMinInterval(schedule cron.Schedule, loc *time.Location, from time.Time) time.Duration {
+	limit := from.Add(time.Hour * 24 * 366)
+
+	last := schedule.Next(from.In(loc))
+	shortest := time.Duration(0)
+	for i := 0; i < 1000 && !last.IsZero() && last.Before(limit); i++ {
+		next := schedule.Next(last)
+		if next.IsZero() {
+			break
+		}
+
+		if diff := next.Sub(last); shortest == 0 || diff < shortest {
+			shortest = diff
+		}
+		last = next
+	}
+
+	return shortest
+}
+
 type NextRunScheduledEvent struct {
 	CmdID int64 `json:"cmd_id"`
 }
@@ -79,7 +134,7 @@ func UpdateCommandNextRunTime(cc *models.CustomCommand, updateLastRun bool) erro
 		return errors.Wrap(err, "del_old_events")
 	}
 
-	if cc.TriggerType != int(CommandTriggerInterval) || cc.TimeTriggerInterval < 1 {
+	if cc.TriggerType != int(CommandTriggerInterval) || (cc.TimeTriggerInterval < 1 && cc.TimeTriggerCron == "") {
 		return nil
 	}
 
@@ -81,3 +81,58 @@ func TestNextRunTimeExcludingDays(t *testing.T) {
 		t.Error("next run should be now: ", expected, ", got: ", nextRun, tim.Weekday())
 	}
 }
+
+func TestNextRunTimeCron(t *testing.T) {
+	cc := &models.CustomCommand{
+		TimeTriggerCron:     "0 9 * * 1-5",
+		TimeTriggerTimezone: "Europe/Berlin",
+	}
+
+	// saturday
+	tim := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// monday 09:00 in Berlin, which is 07:00 UTC during summer time
+	expected := time.Date(2019, 6, 3, 7, 0, 0, 0, time.UTC)
+
+	nextRun := CalcNextRunTime(cc, tim)
+	if !nextRun.Equal(expected) {
+		t.Error("next run should be: ", expected, ", got: ", nextRun)
+	}
+
+	schedule, loc, err := ParseCronSchedule(cc.TimeTriggerCron, cc.TimeTriggerTimezone)
+	if err != nil {
+		t.Fatal("failed parsing schedule: ", err)
+	}
+
+	interval := CronMinInterval(schedule, loc, tim)
+	if interval != time.Hour*24 {
+		t.Error("min interval should be 24h, got: ", interval)
+	}
+}
+
+func TestCronRareSchedules(t *testing.T) {
+	tim := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	schedule, loc, err := ParseCronSchedule("0 0 30 2 *", "UTC")
+	if err != nil {
+		t.Fatal("failed parsing schedule: ", err)
+	}
+
+	if next := schedule.Next(tim.In(loc)); !next.IsZero() {
+		t.Error("schedule on the 30th of february should never run, got: ", next)
+	}
+
+	schedule, loc, err = ParseCronSchedule("0 0 29 2 *", "UTC")
+	if err != nil {
+		t.Fatal("failed parsing schedule: ", err)
+	}
+
+	expected := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+	if next := schedule.Next(tim.In(loc)); !next.Equal(expected) {
+		t.Error("next run should be: ", expected, ", got: ", next)
+	}
+
+	if interval := CronMinInterval(schedule, loc, tim); interval != 0 {
+		t.Error("min interval should be 0 for schedules running less than once a year, got: ", interval)
+	}
+}
@@ -42,6 +42,10 @@ type CustomCommand struct {
 	Roles                     types.Int64Array  `boil:"roles" json:"roles,omitempty" toml:"roles" yaml:"roles,omitempty"`
 	RolesWhitelistMode        bool              `boil:"roles_whitelist_mode" json:"roles_whitelist_mode" toml:"roles_whitelist_mode" yaml:"roles_whitelist_mode"`
 	ContextChannel            int64             `boil:"context_channel" json:"context_channel" toml:"context_channel" yaml:"context_channel"`
+	ReactionTriggerMode       int               `boil:"reaction_trigger_mode" json:"reaction_trigger_mode" toml:"reaction_trigger_mode" yaml:"reaction_trigger_mode"`
+	ReactionTriggerMessage    int64             `boil:"reaction_trigger_message" json:"reaction_trigger_message" toml:"reaction_trigger_message" yaml:"reaction_trigger_message"`
+	TimeTriggerCron           string            `boil:"time_trigger_cron" json:"time_trigger_cron" toml:"time_trigger_cron" yaml:"time_trigger_cron"`
+	TimeTriggerTimezone       string            `boil:"time_trigger_timezone" json:"time_trigger_timezone" toml:"time_trigger_timezone" yaml:"time_trigger_timezone"`
 
 	R *customCommandR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L customCommandL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -65,6 +69,10 @@ var CustomCommandColumns = struct {
 	Roles                     string
 	RolesWhitelistMode        string
 	ContextChannel            string
+	ReactionTriggerMode       string
+	ReactionTriggerMessage    string
+	TimeTriggerCron           string
+	TimeTriggerTimezone       string
 }{
 	LocalID:                   "local_id",
 	GuildID:                   "guild_id",
@@ -83,6 +91,10 @@ var CustomCommandColumns = struct {
 	Roles:                     "roles",
 	RolesWhitelistMode:        "roles_whitelist_mode",
 	ContextChannel:            "context_channel",
+	ReactionTriggerMode:       "reaction_trigger_mode",
+	ReactionTriggerMessage:    "reaction_trigger_message",
+	TimeTriggerCron:           "time_trigger_cron",
+	TimeTriggerTimezone:       "time_trigger_timezone",
 }
 
 // Generated where
@@ -190,6 +202,10 @@ var CustomCommandWhere = struct {
 	Roles                     whereHelpertypes_Int64Array
 	RolesWhitelistMode        whereHelperbool
 	ContextChannel            whereHelperint64
+	ReactionTriggerMode       whereHelperint
+	ReactionTriggerMessage    whereHelperint64
+	TimeTriggerCron           whereHelperstring
+	TimeTriggerTimezone       whereHelperstring
 }{
 	LocalID:                   whereHelperint64{field: "\"custom_commands\".\"local_id\""},
 	GuildID:                   whereHelperint64{field: "\"custom_commands\".\"guild_id\""},
@@ -208,6 +224,10 @@ var CustomCommandWhere = struct {
 	Roles:                     whereHelpertypes_Int64Array{field: "\"custom_commands\".\"roles\""},
 	RolesWhitelistMode:        whereHelperbool{field: "\"custom_commands\".\"roles_whitelist_mode\""},
 	ContextChannel:            whereHelperint64{field: "\"custom_commands\".\"context_channel\""},
+	ReactionTriggerMode:       whereHelperint{field: "\"custom_commands\".\"reaction_trigger_mode\""},
+	ReactionTriggerMessage:    whereHelperint64{field: "\"custom_commands\".\"reaction_trigger_message\""},
+	TimeTriggerCron:           whereHelperstring{field: "\"custom_commands\".\"time_trigger_cron\""},
+	TimeTriggerTimezone:       whereHelperstring{field: "\"custom_commands\".\"time_trigger_timezone\""},
 }
 
 // CustomCommandRels is where relationship names are stored.
@@ -231,9 +251,9 @@ func (*customCommandR) NewStruct() *customCommandR {
 type customCommandL struct{}
 
 var (
-	customCommandAllColumns            = []string{"local_id", "guild_id", "group_id", "trigger_type", "text_trigger", "text_trigger_case_sensitive", "time_trigger_interval", "time_trigger_excluding_days", "time_trigger_excluding_hours", "last_run", "next_run", "responses", "channels", "channels_whitelist_mode", "roles", "roles_whitelist_mode", "context_channel"}
+	customCommandAllColumns            = []string{"local_id", "guild_id", "group_id", "trigger_type", "text_trigger", "text_trigger_case_sensitive", "time_trigger_interval", "time_trigger_excluding_days", "time_trigger_excluding_hours", "last_run", "next_run", "responses", "channels", "channels_whitelist_mode", "roles", "roles_whitelist_mode", "context_channel", "reaction_trigger_mode", "reaction_trigger_message", "time_trigger_cron", "time_trigger_timezone"}
 	customCommandColumnsWithoutDefault = []string{"local_id", "guild_id", "group_id", "trigger_type", "text_trigger", "text_trigger_case_sensitive", "time_trigger_interval", "time_trigger_excluding_days", "time_trigger_excluding_hours", "last_run", "next_run", "responses", "channels", "channels_whitelist_mode", "roles", "roles_whitelist_mode"}
-	customCommandColumnsWithDefault    = []string{"context_channel"}
+	customCommandColumnsWithDefault    = []string{"context_channel", "reaction_trigger_mode", "reaction_trigger_message", "time_trigger_cron", "time_trigger_timezone"}
 	customCommandPrimaryKeyColumns     = []string{"guild_id", "local_id"}
 )
 
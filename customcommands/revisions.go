@@ -0,0 +1,283 @@
+package customcommands
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/customcommands/models"
+	"github.com/lib/pq"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+const (
+	// MaxRevisions is the number of revisions kept per custom command, older ones are removed when a new one is saved
+	MaxRevisions = 25
+
+	// MaxDiffCells is the max number of lines in a times the lines in b that DiffLines does a full diff on,
+	// above it the changed lines are shown as all removed and then all added
+	MaxDiffCells = 250000
+)
+
+// Revision is a saved version of the responses of a custom command
+type Revision struct {
+	ID        int64
+	CreatedAt time.Time
+
+	GuildID int64
+	CmdID   int64
+
+	AuthorID       int64
+	AuthorUsername string
+
+	Responses []string
+}
+
+// SaveRevision stores the current responses of the command as a new revision, unless they're the same as the latest one
+func SaveRevision(ctx context.Context, cmd *models.CustomCommand, author *discordgo.User) error {
+	latest, err := GetRevisions(ctx, cmd.GuildID, cmd.LocalID, 1)
+	if err != nil {
+		return err
+	}
+
+	if len(latest) > 0 && responsesEqual(latest[0].Responses, cmd.Responses) {
+		return nil
+	}
+
+	err = insertRevision(ctx, cmd.GuildID, cmd.LocalID, author.ID, author.Username+"#"+author.Discriminator, cmd.Responses)
+	if err != nil {
+		return err
+	}
+
+	const qPrune = `DELETE FROM custom_command_revisions WHERE guild_id = $1 AND local_id = $2 AND id NOT IN (
+	SELECT id FROM custom_command_revisions WHERE guild_id = $1 AND local_id = $2 ORDER BY id DESC LIMIT $3
+);`
+
+	_, err = common.PQ.ExecContext(ctx, qPrune, cmd.GuildID, cmd.LocalID, MaxRevisions)
+	return err
+}
+
+// SaveInitialRevision stores the responses currently in the database as a revision if the command has none yet,
+// so the version from before revisions were kept isn't lost on the first edit
+func SaveInitialRevision(ctx context.Context, guildID, cmdID int64) error {
+	latest, err := GetRevisions(ctx, guildID, cmdID, 1)
+	if err != nil || len(latest) > 0 {
+		return err
+	}
+
+	current, err := models.CustomCommands(qm.Where("guild_id = ? AND local_id = ?", guildID, cmdID)).OneG(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	// the author of it is unknown
+	return insertRevision(ctx, guildID, cmdID, 0, "Unknown", current.Responses)
+}
+
+func insertRevision(ctx context.Context, guildID, cmdID, authorID int64, authorUsername string, responses []string) error {
+	const q = `INSERT INTO custom_command_revisions (created_at, guild_id, local_id, author_id, author_username, responses)
+VALUES ($1, $2, $3, $4, $5, $6);`
+
+	_, err := common.PQ.ExecContext(ctx, q, time.Now(), guildID, cmdID, authorID, authorUsername, pq.Array(responses))
+	return err
+}
+
+// GetRevisions returns the revisions of a command, newest first
+func GetRevisions(ctx context.Context, guildID, cmdID int64, limit int) ([]*Revision, error) {
+	const q = `SELECT id, created_at, guild_id, local_id, author_id, author_username, responses FROM custom_command_revisions
+WHERE guild_id = $1 AND local_id = $2 ORDER BY id DESC LIMIT $3;`
+
+	rows, err := common.PQ.QueryContext(ctx, q, guildID, cmdID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*Revision
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, rev)
+	}
+
+	return result, rows.Err()
+}
+
+// GetRevision returns a single revision of a command, or nil if it does not exist
+func GetRevision(ctx context.Context, guildID, cmdID, revisionID int64) (*Revision, error) {
+	const q = `SELECT id, created_at, guild_id, local_id, author_id, author_username, responses FROM custom_command_revisions
+WHERE guild_id = $1 AND local_id = $2 AND id = $3;`
+
+	rev, err := scanRevision(common.PQ.QueryRowContext(ctx, q, guildID, cmdID, revisionID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return rev, err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRevision(row rowScanner) (*Revision, error) {
+	rev := &Revision{}
+	err := row.Scan(&rev.ID, &rev.CreatedAt, &rev.GuildID, &rev.CmdID, &rev.AuthorID, &rev.AuthorUsername, pq.Array(&rev.Responses))
+	if err != nil {
+		return nil, err
+	}
+
+	return rev, nil
+}
+
+func responsesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type DiffOp int
+
+const (
+	DiffOpEqual   DiffOp = 0
+	DiffOpAdded   DiffOp = 1
+	DiffOpRemoved DiffOp = 2
+)
+
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// DiffLines returns a line based diff turning a into b
+func DiffLines(a, b string) []DiffLine {
+	linesA := strings.Split(a, "\n")
+	linesB := strings.Split(b, "\n")
+	if a == "" {
+		linesA = nil
+	}
+	if b == "" {
+		linesB = nil
+	}
+
+	// the unchanged lines at the start and end don't need to be diffed
+	prefix := 0
+	for prefix < len(linesA) && prefix < len(linesB) && linesA[prefix] == linesB[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(linesA)-prefix && suffix < len(linesB)-prefix && linesA[len(linesA)-1-suffix] == linesB[len(linesB)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]DiffLine, 0, len(linesA)+len(linesB))
+	for _, v := range linesA[:prefix] {
+		result = append(result, DiffLine{Op: DiffOpEqual, Text: v})
+	}
+
+	result = append(result, diffLinesLCS(linesA[prefix:len(linesA)-suffix], linesB[prefix:len(linesB)-suffix])...)
+
+	for _, v := range linesA[len(linesA)-suffix:] {
+		result = append(result, DiffLine{Op: DiffOpEqual, Text: v})
+	}
+
+	return result
+}
+
+// diffLinesLCS diffs the lines using the longest common subsequence, unless it's too big
+func diffLinesLCS(linesA, linesB []string) []DiffLine {
+	result := make([]DiffLine, 0, len(linesA)+len(linesB))
+	if len(linesA)*len(linesB) > MaxDiffCells {
+		for _, v := range linesA {
+			result = append(result, DiffLine{Op: DiffOpRemoved, Text: v})
+		}
+
+		for _, v := range linesB {
+			result = append(result, DiffLine{Op: DiffOpAdded, Text: v})
+		}
+
+		return result
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of linesA[i:] and linesB[j:]
+	width := len(linesB) + 1
+	lcs := make([]int, (len(linesA)+1)*width)
+	for i := len(linesA) - 1; i >= 0; i-- {
+		for j := len(linesB) - 1; j >= 0; j-- {
+			if linesA[i] == linesB[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else if lcs[(i+1)*width+j] >= lcs[i*width+j+1] {
+				lcs[i*width+j] = lcs[(i+1)*width+j]
+			} else {
+				lcs[i*width+j] = lcs[i*width+j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(linesA) && j < len(linesB) {
+		switch {
+		case linesA[i] == linesB[j]:
+			result = append(result, DiffLine{Op: DiffOpEqual, Text: linesA[i]})
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			result = append(result, DiffLine{Op: DiffOpRemoved, Text: linesA[i]})
+			i++
+		default:
+			result = append(result, DiffLine{Op: DiffOpAdded, Text: linesB[j]})
+			j++
+		}
+	}
+
+	for ; i < len(linesA); i++ {
+		result = append(result, DiffLine{Op: DiffOpRemoved, Text: linesA[i]})
+	}
+
+	for ; j < len(linesB); j++ {
+		result = append(result, DiffLine{Op: DiffOpAdded, Text: linesB[j]})
+	}
+
+	return result
+}
+
+// DiffResponses diffs each response of a against the response at the same position in b
+func DiffResponses(a, b []string) [][]DiffLine {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+
+	result := make([][]DiffLine, n)
+	for i := 0; i < n; i++ {
+		var respA, respB string
+		if i < len(a) {
+			respA = a[i]
+		}
+		if i < len(b) {
+			respB = b[i]
+		}
+
+		result[i] = DiffLines(respA, respB)
+	}
+
+	return result
+}
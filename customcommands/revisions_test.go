@@ -0,0 +1,68 @@
+package customcommands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []DiffLine
+	}{
+		{
+			"a\nb\nc",
+			"a\nb\nc",
+			[]DiffLine{{DiffOpEqual, "a"}, {DiffOpEqual, "b"}, {DiffOpEqual, "c"}},
+		},
+		{
+			"a\nb\nc",
+			"a\nc\nd",
+			[]DiffLine{{DiffOpEqual, "a"}, {DiffOpRemoved, "b"}, {DiffOpEqual, "c"}, {DiffOpAdded, "d"}},
+		},
+		{
+			"",
+			"a\nb",
+			[]DiffLine{{DiffOpAdded, "a"}, {DiffOpAdded, "b"}},
+		},
+		{
+			"a\nb",
+			"",
+			[]DiffLine{{DiffOpRemoved, "a"}, {DiffOpRemoved, "b"}},
+		},
+	}
+
+	// too big for a full diff, only the prefix and suffix are found
+	big := strings.Repeat("x\n", 1000)
+	tests = append(tests, struct {
+		a, b string
+		want []DiffLine
+	}{
+		"a\n" + big + "c",
+		"a\n" + strings.Repeat("y\n", 1000) + "c",
+		append(append(append([]DiffLine{{DiffOpEqual, "a"}}, repeatDiffLine(DiffOpRemoved, "x", 1000)...), repeatDiffLine(DiffOpAdded, "y", 1000)...), DiffLine{DiffOpEqual, "c"}),
+	})
+
+	for i, test := range tests {
+		got := DiffLines(test.a, test.b)
+		if len(got) != len(test.want) {
+			t.Errorf("%d: got %v, want %v", i, got, test.want)
+			continue
+		}
+
+		for j, v := range test.want {
+			if got[j] != v {
+				t.Errorf("%d: line %d: got %v, want %v", i, j, got[j], v)
+			}
+		}
+	}
+}
+
+func repeatDiffLine(op DiffOp, text string, n int) []DiffLine {
+	result := make([]DiffLine, n)
+	for i := range result {
+		result[i] = DiffLine{Op: op, Text: text}
+	}
+
+	return result
+}
@@ -71,4 +71,29 @@ CREATE TABLE IF NOT EXISTS templates_user_database (
 CREATE INDEX IF NOT EXISTS templates_user_database_combined_idx ON templates_user_database (guild_id, user_id, key, value_num);
 `, `
 CREATE INDEX IF NOT EXISTS templates_user_database_expires_idx ON templates_user_database (expires_at);
+`, `
+ALTER TABLE custom_commands ADD COLUMN IF NOT EXISTS reaction_trigger_mode INT NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE custom_commands ADD COLUMN IF NOT EXISTS reaction_trigger_message BIGINT NOT NULL DEFAULT 0;
+`, `
+ALTER TABLE custom_commands ADD COLUMN IF NOT EXISTS time_trigger_cron TEXT NOT NULL DEFAULT '';
+`, `
+ALTER TABLE custom_commands ADD COLUMN IF NOT EXISTS time_trigger_timezone TEXT NOT NULL DEFAULT '';
+`, `
+CREATE TABLE IF NOT EXISTS custom_command_revisions (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+	guild_id BIGINT NOT NULL,
+	local_id BIGINT NOT NULL,
+
+	author_id BIGINT NOT NULL,
+	author_username TEXT NOT NULL,
+
+	responses TEXT[] NOT NULL,
+
+	FOREIGN KEY (guild_id, local_id) REFERENCES custom_commands(guild_id, local_id) ON DELETE CASCADE
+);
+`, `
+CREATE INDEX IF NOT EXISTS custom_command_revisions_cmd_idx ON custom_command_revisions(guild_id, local_id);
 `}
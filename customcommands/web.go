@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"unicode/utf8"
 
+	"github.com/jonas747/discordgo"
 	"github.com/jonas747/yagpdb/common"
 	"github.com/jonas747/yagpdb/common/pubsub"
 	"github.com/jonas747/yagpdb/customcommands/models"
@@ -57,6 +58,10 @@ func (p *Plugin) InitWeb() {
 	subMux.Handle(pat.Post("/commands/:cmd/update"), web.ControllerPostHandler(HandleUpdateCommand, getHandler, CustomCommand{}, "Updated a custom command"))
 	subMux.Handle(pat.Post("/commands/:cmd/delete"), web.ControllerPostHandler(HandleDeleteCommand, getHandler, nil, "Deleted a custom command"))
 
+	revisionsHandler := web.ControllerHandler(HandleGetCommandRevisions, "cp_custom_command_revisions")
+	subMux.Handle(pat.Get("/commands/:cmd/revisions"), revisionsHandler)
+	subMux.Handle(pat.Post("/commands/:cmd/revisions/:rev/restore"), web.ControllerPostHandler(HandleRestoreRevision, revisionsHandler, nil, "Restored a custom command revision"))
+
 	subMux.Handle(pat.Post("/creategroup"), web.ControllerPostHandler(HandleNewGroup, getHandler, GroupForm{}, "Created a new custom command group"))
 	subMux.Handle(pat.Post("/groups/:group/update"), web.ControllerPostHandler(HandleUpdateGroup, getGroupHandler, GroupForm{}, "Updated a custom command group"))
 	subMux.Handle(pat.Post("/groups/:group/delete"), web.ControllerPostHandler(HandleDeleteGroup, getHandler, nil, "Deleted a custom command group"))
@@ -99,6 +104,22 @@ func ServeGroupSelected(r *http.Request, templateData web.TemplateData, groupID
 		templateData["CustomCommands"] = commands
 	}
 
+	commands := templateData["CustomCommands"].([]*models.CustomCommand)
+	ids := make([]int64, len(commands))
+	for i, v := range commands {
+		ids[i] = v.LocalID
+	}
+
+	// the execution logs are only informational, so don't fail the whole page over them
+	execLogs, err := GetExecLogs(guildID, ids)
+	if err != nil {
+		web.CtxLogger(r.Context()).WithError(err).WithField("guild", guildID).Error("failed retrieving custom command execution logs")
+		execLogs = map[int64][]*ExecLogEntry{}
+		templateData.AddAlerts(web.WarningAlert("Failed retrieving the execution logs, try again later"))
+	}
+	templateData["ExecLogs"] = execLogs
+	templateData["MaxExecLogEntries"] = MaxExecLogEntries
+
 	commandsGroups, err := models.CustomCommandGroups(qm.Where("guild_id = ?", guildID), qm.OrderBy("id asc")).AllG(r.Context())
 	if err != nil {
 		return templateData, err
@@ -168,6 +189,8 @@ func HandleNewCommand(w http.ResponseWriter, r *http.Request) (web.TemplateData,
 		return templateData, err
 	}
 
+	saveRevisionFromContext(ctx, dbModel)
+
 	if dbModel.TriggerType == int(CommandTriggerInterval) {
 		// create, update or remove the next run time and scheduled event
 		err = UpdateCommandNextRunTime(dbModel, true)
@@ -214,11 +237,19 @@ func HandleUpdateCommand(w http.ResponseWriter, r *http.Request) (web.TemplateDa
 		}
 	}
 
-	_, err := dbModel.UpdateG(ctx, boil.Blacklist("last_run", "next_run", "local_id", "guild_id"))
+	// keep the version being overwritten if it was made before revisions were kept
+	err := SaveInitialRevision(ctx, activeGuild.ID, dbModel.LocalID)
+	if err != nil {
+		web.CtxLogger(ctx).WithError(err).WithField("guild", activeGuild.ID).Error("failed saving initial custom command revision")
+	}
+
+	_, err = dbModel.UpdateG(ctx, boil.Blacklist("last_run", "next_run", "local_id", "guild_id"))
 	if err != nil {
 		return templateData, nil
 	}
 
+	saveRevisionFromContext(ctx, dbModel)
+
 	// create, update or remove the next run time and scheduled event
 	if dbModel.TriggerType == int(CommandTriggerInterval) {
 		// need the last run time
@@ -264,11 +295,95 @@ func HandleDeleteCommand(w http.ResponseWriter, r *http.Request) (web.TemplateDa
 		return templateData, err
 	}
 
+	common.LogIgnoreError(DelExecLog(cmd.GuildID, cmd.LocalID), "failed deleting custom command exec log", web.CtxLogger(ctx).Data)
+
 	err = DelNextRunEvent(cmd.GuildID, cmd.LocalID)
 	common.LogIgnoreError(pubsub.Publish("custom_commands_clear_cache", activeGuild.ID, nil), "failed creating pubsub cache eviction event", web.CtxLogger(ctx).Data)
 	return templateData, err
 }
 
+// saveRevisionFromContext stores the responses of the command as a new revision authored by the logged in user
+func saveRevisionFromContext(ctx context.Context, cmd *models.CustomCommand) {
+	user, ok := ctx.Value(common.ContextKeyUser).(*discordgo.User)
+	if !ok {
+		return
+	}
+
+	err := SaveRevision(ctx, cmd, user)
+	if err != nil {
+		web.CtxLogger(ctx).WithError(err).WithField("guild", cmd.GuildID).Error("failed saving custom command revision")
+	}
+}
+
+func HandleGetCommandRevisions(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	activeGuild, templateData := web.GetBaseCPContextData(ctx)
+
+	cmdID, err := strconv.ParseInt(pat.Param(r, "cmd"), 10, 64)
+	if err != nil {
+		return templateData, err
+	}
+
+	cmd, err := models.CustomCommands(qm.Where("guild_id = ? AND local_id = ?", activeGuild.ID, cmdID)).OneG(ctx)
+	if err != nil {
+		return templateData, err
+	}
+
+	revisions, err := GetRevisions(ctx, activeGuild.ID, cmdID, MaxRevisions)
+	if err != nil {
+		return templateData, err
+	}
+
+	templateData["Command"] = cmd
+	templateData["Revisions"] = revisions
+	templateData["MaxRevisions"] = MaxRevisions
+
+	// the diff shows what restoring the selected revision would change
+	revID, _ := strconv.ParseInt(r.FormValue("rev"), 10, 64)
+	for _, v := range revisions {
+		if v.ID == revID {
+			templateData["SelectedRevision"] = v
+			templateData["Diff"] = DiffResponses(cmd.Responses, v.Responses)
+			break
+		}
+	}
+
+	return templateData, nil
+}
+
+func HandleRestoreRevision(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	activeGuild, templateData := web.GetBaseCPContextData(ctx)
+
+	cmdID, _ := strconv.ParseInt(pat.Param(r, "cmd"), 10, 64)
+	revID, _ := strconv.ParseInt(pat.Param(r, "rev"), 10, 64)
+
+	cmd, err := models.CustomCommands(qm.Where("guild_id = ? AND local_id = ?", activeGuild.ID, cmdID)).OneG(ctx)
+	if err != nil {
+		return templateData, err
+	}
+
+	rev, err := GetRevision(ctx, activeGuild.ID, cmdID, revID)
+	if err != nil {
+		return templateData, err
+	}
+
+	if rev == nil {
+		return templateData, web.NewPublicError("Revision not found")
+	}
+
+	cmd.Responses = rev.Responses
+	_, err = cmd.UpdateG(ctx, boil.Whitelist("responses"))
+	if err != nil {
+		return templateData, err
+	}
+
+	saveRevisionFromContext(ctx, cmd)
+
+	common.LogIgnoreError(pubsub.Publish("custom_commands_clear_cache", activeGuild.ID, nil), "failed creating pubsub cache eviction event", web.CtxLogger(ctx).Data)
+	return templateData, nil
+}
+
 // allow for max 5 triggers with intervals of less than 10 minutes
 func CheckIntervalLimits(ctx context.Context, guildID int64, cmdID int64, templateData web.TemplateData) (ok bool, err error) {
 	num, err := models.CustomCommands(qm.Where("guild_id = ? AND local_id != ? AND trigger_type = 5 AND time_trigger_interval < 10", guildID, cmdID)).CountG(ctx)
@@ -369,8 +484,14 @@ func TriggerTypeFromForm(str string) CommandTriggerType {
 		return CommandTriggerExact
 	case "command":
 		return CommandTriggerCommand
-	case "interval_minutes", "interval_hours":
+	case "interval_minutes", "interval_hours", "interval_cron":
 		return CommandTriggerInterval
+	case "reaction":
+		return CommandTriggerReaction
+	case "join":
+		return CommandTriggerJoin
+	case "leave":
+		return CommandTriggerLeave
 	default:
 		return CommandTriggerCommand
 
@@ -386,12 +507,16 @@ func CheckLimits(in ...string) bool {
 	return true
 }
 
-// returns 1 for hours, 0 for minutes, -1 otherwise
+// returns 2 for cron, 1 for hours, 0 for minutes, -1 otherwise
 func tmplGetCCIntervalTriggerType(cc *models.CustomCommand) int {
 	if cc.TriggerType != int(CommandTriggerInterval) {
 		return -1
 	}
 
+	if cc.TimeTriggerCron != "" {
+		return 2
+	}
+
 	if (cc.TimeTriggerInterval % 60) == 0 {
 		return 1
 	}
@@ -0,0 +1,289 @@
+package moderation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/bot/eventsystem"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/web"
+	"github.com/pkg/errors"
+)
+
+const (
+	AppealKindBan  = "ban"
+	AppealKindMute = "mute"
+
+	AppealStatusPending  = "pending"
+	AppealStatusApproved = "approved"
+	AppealStatusDenied   = "denied"
+
+	EmojiAppealApprove = "✅"
+	EmojiAppealDeny    = "❌"
+
+	// how long a user has to wait after a denied appeal before they can appeal again
+	AppealCooldown = time.Hour * 24
+)
+
+// AppealModel is an appeal submitted through the dashboard by a banned or muted user
+type AppealModel struct {
+	common.SmallModel
+
+	GuildID             int64 `gorm:"index"`
+	UserID              int64 `gorm:"index"`
+	UserUsernameDiscrim string
+
+	// AppealKindBan or AppealKindMute
+	Kind    string
+	Message string
+	// One of the AppealStatus constants
+	Status string
+
+	// The message in the appeals channel
+	ChannelID int64
+	MessageID int64 `gorm:"index"`
+
+	HandledByID              int64
+	HandledByUsernameDiscrim string
+}
+
+func (a *AppealModel) TableName() string {
+	return "moderation_appeals"
+}
+
+// AppealURL returns the link to the appeal form of a server
+func AppealURL(guildID int64) string {
+	return fmt.Sprintf("%s/public/%d/appeal", web.BaseURL(), guildID)
+}
+
+// UserAppealKind returns what the user can appeal, an empty string if they're neither banned nor muted
+func UserAppealKind(guildID, userID int64) (string, error) {
+	_, err := common.BotSession.GuildBan(guildID, userID)
+	if err == nil {
+		return AppealKindBan, nil
+	}
+
+	if cast, ok := err.(*discordgo.RESTError); !ok || cast.Response == nil || cast.Response.StatusCode != 404 {
+		return "", err
+	}
+
+	var mute MuteModel
+	err = common.GORM.Where(&MuteModel{UserID: userID, GuildID: guildID}).First(&mute).Error
+	if err == nil {
+		return AppealKindMute, nil
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		return "", nil
+	}
+
+	return "", err
+}
+
+// checkCanAppeal returns what the user can appeal, or a message explaining why they can't appeal right now
+func checkCanAppeal(guildID, userID int64) (kind string, blocked string, err error) {
+	var latest AppealModel
+	err = common.GORM.Where("guild_id = ? AND user_id = ?", guildID, userID).Order("id desc").First(&latest).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return "", "", err
+	}
+
+	if err == nil {
+		if latest.Status == AppealStatusPending {
+			return "", "You already have a pending appeal, you will get a DM when it has been handled", nil
+		}
+
+		if latest.Status == AppealStatusDenied && time.Since(latest.UpdatedAt) < AppealCooldown {
+			wait := common.HumanizeDuration(common.DurationPrecisionMinutes, AppealCooldown-time.Since(latest.UpdatedAt))
+			return "", "Your last appeal was denied, you can appeal again in " + wait, nil
+		}
+	}
+
+	kind, err = UserAppealKind(guildID, userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if kind == "" {
+		return "", "You're not banned or muted on this server", nil
+	}
+
+	return kind, "", nil
+}
+
+func appealEmbed(appeal *AppealModel) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("%s appeal from %s (%d)", appeal.Kind, appeal.UserUsernameDiscrim, appeal.UserID),
+		Description: appeal.Message,
+		Timestamp:   appeal.CreatedAt.Format(time.RFC3339),
+		Color:       0xf2a013,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("React with %s to approve or %s to deny", EmojiAppealApprove, EmojiAppealDeny),
+		},
+	}
+
+	switch appeal.Status {
+	case AppealStatusApproved:
+		embed.Color = 0x62c65f
+		embed.Footer.Text = "Approved by " + appeal.HandledByUsernameDiscrim
+	case AppealStatusDenied:
+		embed.Color = 0xd64848
+		embed.Footer.Text = "Denied by " + appeal.HandledByUsernameDiscrim
+	}
+
+	return embed
+}
+
+// ErrAppealPending is returned by PostAppeal if the user already has a pending appeal
+var ErrAppealPending = errors.New("user already has a pending appeal")
+
+// PostAppeal creates the appeal and posts it in the appeals channel.
+// The appeal is inserted first so the unique index on pending appeals catches double submits.
+func PostAppeal(config *Config, guildID int64, user *discordgo.User, kind, message string) (*AppealModel, error) {
+	appeal := &AppealModel{
+		GuildID:             guildID,
+		UserID:              user.ID,
+		UserUsernameDiscrim: user.Username + "#" + user.Discriminator,
+		Kind:                kind,
+		Message:             message,
+		Status:              AppealStatusPending,
+		ChannelID:           config.IntAppealsChannel(),
+	}
+
+	err := common.GORM.Create(appeal).Error
+	if err != nil {
+		if common.ErrPQIsUniqueViolation(err) {
+			return nil, ErrAppealPending
+		}
+		return nil, err
+	}
+
+	msg, err := common.BotSession.ChannelMessageSendEmbed(appeal.ChannelID, appealEmbed(appeal))
+	if err != nil {
+		common.GORM.Delete(appeal)
+		return nil, err
+	}
+
+	appeal.MessageID = msg.ID
+	err = common.GORM.Model(appeal).Update("message_id", msg.ID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	common.BotSession.MessageReactionAdd(msg.ChannelID, msg.ID, EmojiAppealApprove)
+	common.BotSession.MessageReactionAdd(msg.ChannelID, msg.ID, EmojiAppealDeny)
+
+	return appeal, nil
+}
+
+// memberCanHandleAppeal checks if the member could perform the action being appealed
+func memberCanHandleAppeal(config *Config, ms *dstate.MemberState, channelID int64, kind string) bool {
+	perm := discordgo.PermissionBanMembers
+	roles := config.BanCmdRoles
+	if kind == AppealKindMute {
+		perm = discordgo.PermissionKickMembers
+		roles = config.MuteCmdRoles
+	}
+
+	if common.ContainsInt64SliceOneOf(roles, ms.Roles) {
+		return true
+	}
+
+	hasPerms, err := bot.AdminOrPermMS(ms, channelID, perm)
+	return err == nil && hasPerms
+}
+
+func HandleAppealReaction(evt *eventsystem.EventData) {
+	ra := evt.MessageReactionAdd()
+	if ra.GuildID == 0 || ra.UserID == common.BotUser.ID {
+		return
+	}
+
+	if ra.Emoji.Name != EmojiAppealApprove && ra.Emoji.Name != EmojiAppealDeny {
+		return
+	}
+
+	var appeal AppealModel
+	err := common.GORM.Where("guild_id = ? AND message_id = ? AND status = ?", ra.GuildID, ra.MessageID, AppealStatusPending).First(&appeal).Error
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			logger.WithError(err).WithField("guild", ra.GuildID).Error("failed retrieving appeal")
+		}
+		return
+	}
+
+	config, err := GetConfig(ra.GuildID)
+	if err != nil {
+		logger.WithError(err).WithField("guild", ra.GuildID).Error("failed retrieving config")
+		return
+	}
+
+	member, err := bot.GetMember(ra.GuildID, ra.UserID)
+	if err != nil || member == nil {
+		return
+	}
+
+	if !memberCanHandleAppeal(config, member, ra.ChannelID, appeal.Kind) {
+		return
+	}
+
+	status := AppealStatusDenied
+	if ra.Emoji.Name == EmojiAppealApprove {
+		status = AppealStatusApproved
+	}
+
+	author := member.DGoUser()
+
+	// only the first moderator to react handles it
+	rows := common.GORM.Model(&AppealModel{}).Where("id = ? AND status = ?", appeal.ID, AppealStatusPending).Updates(map[string]interface{}{
+		"status":                      status,
+		"handled_by_id":               author.ID,
+		"handled_by_username_discrim": author.Username + "#" + author.Discriminator,
+	}).RowsAffected
+	if rows < 1 {
+		return
+	}
+
+	appeal.Status = status
+	appeal.HandledByID = author.ID
+	appeal.HandledByUsernameDiscrim = author.Username + "#" + author.Discriminator
+
+	if status == AppealStatusApproved {
+		err = approveAppeal(config, &appeal, author)
+		if err != nil {
+			logger.WithError(err).WithField("guild", ra.GuildID).Error("failed lifting punishment of approved appeal")
+			common.BotSession.ChannelMessageSend(ra.ChannelID, fmt.Sprintf("Failed lifting the %s of %s: %s", appeal.Kind, appeal.UserUsernameDiscrim, err.Error()))
+		}
+	}
+
+	_, err = common.BotSession.ChannelMessageEditEmbed(ra.ChannelID, ra.MessageID, appealEmbed(&appeal))
+	if err != nil {
+		logger.WithError(err).WithField("guild", ra.GuildID).Error("failed updating appeal message")
+	}
+
+	go bot.SendDM(appeal.UserID, fmt.Sprintf("**%s:** Your %s appeal was %s", bot.GuildName(ra.GuildID), appeal.Kind, status))
+}
+
+func approveAppeal(config *Config, appeal *AppealModel, author *discordgo.User) error {
+	reason := "Appeal approved"
+
+	if appeal.Kind == AppealKindBan {
+		users := bot.GetUsers(appeal.GuildID, appeal.UserID)
+		if len(users) < 1 {
+			return errors.New("failed retrieving user")
+		}
+
+		return UnbanUser(config, appeal.GuildID, author, reason, users[0])
+	}
+
+	member, err := bot.GetMember(appeal.GuildID, appeal.UserID)
+	if err != nil {
+		return err
+	}
+
+	return MuteUnmuteUser(config, false, appeal.GuildID, 0, author, reason, member, 0)
+}
@@ -0,0 +1,103 @@
+package moderation
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jonas747/yagpdb/common"
+)
+
+// MaxAuditEntries is the max number of cases returned by the audit page and exports
+const MaxAuditEntries = 10000
+
+// AuditFilter selects moderation cases within a time range, zero values are ignored
+type AuditFilter struct {
+	From   time.Time
+	To     time.Time
+	Author int64
+	Target int64
+	Action string
+}
+
+func (f *AuditFilter) query(guildID int64) *gorm.DB {
+	q := common.GORM.Model(&CaseModel{}).Where("guild_id = ? AND created_at >= ? AND created_at < ?", guildID, f.From, f.To)
+	if f.Author != 0 {
+		q = q.Where("author_id = ?", f.Author)
+	}
+	if f.Target != 0 {
+		q = q.Where("user_id = ?", f.Target)
+	}
+	if f.Action != "" {
+		q = q.Where("lower(action) = lower(?)", strings.TrimSpace(f.Action))
+	}
+
+	return q
+}
+
+// RetrieveAuditCases returns the cases matching the filter, newest first
+func RetrieveAuditCases(guildID int64, filter *AuditFilter) ([]*CaseModel, error) {
+	var result []*CaseModel
+	err := filter.query(guildID).Order("case_number desc").Limit(MaxAuditEntries).Find(&result).Error
+	if err == gorm.ErrRecordNotFound {
+		err = nil
+	}
+
+	return result, err
+}
+
+type ModeratorStats struct {
+	AuthorID              int64          `json:"author_id,string"`
+	AuthorUsernameDiscrim string         `json:"author_username_discrim"`
+	Total                 int            `json:"total"`
+	Actions               map[string]int `json:"actions"`
+}
+
+// RetrieveModeratorStats returns the number of actions per moderator matching the filter, sorted by most actions
+func RetrieveModeratorStats(guildID int64, filter *AuditFilter) ([]*ModeratorStats, error) {
+	rows, err := filter.query(guildID).Select("author_id, max(author_username_discrim), action, count(*)").Group("author_id, action").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byAuthor := make(map[int64]*ModeratorStats)
+	for rows.Next() {
+		var authorID int64
+		var usernameDiscrim, action string
+		var count int
+		err = rows.Scan(&authorID, &usernameDiscrim, &action, &count)
+		if err != nil {
+			return nil, err
+		}
+
+		stats, ok := byAuthor[authorID]
+		if !ok {
+			stats = &ModeratorStats{
+				AuthorID:              authorID,
+				AuthorUsernameDiscrim: usernameDiscrim,
+				Actions:               make(map[string]int),
+			}
+			byAuthor[authorID] = stats
+		}
+
+		stats.Actions[action] += count
+		stats.Total += count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]*ModeratorStats, 0, len(byAuthor))
+	for _, v := range byAuthor {
+		result = append(result, v)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Total > result[j].Total
+	})
+
+	return result, nil
+}
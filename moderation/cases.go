@@ -0,0 +1,290 @@
+package moderation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jonas747/dcmd"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/commands"
+	"github.com/jonas747/yagpdb/common"
+)
+
+const (
+	ActionGaveRole         = "Gave role"
+	ActionRemovedRole      = "Removed role"
+	ActionTempbanUpdated   = "Updated tempban"
+	ActionTempbanCancelled = "Cancelled tempban"
+)
+
+// CaseModel is a record of a moderation action against a user, each server has its own incrementing case numbers
+type CaseModel struct {
+	common.SmallModel
+
+	// ID fields are encoded as strings in json as they don't fit in javascript numbers
+	GuildID    int64 `gorm:"unique_index:moderation_cases_guild_case_number_idx" json:",string"`
+	CaseNumber int64 `gorm:"unique_index:moderation_cases_guild_case_number_idx"`
+
+	// One of the Action constants
+	Action string
+
+	UserID              int64 `gorm:"index" json:",string"`
+	UserUsernameDiscrim string
+
+	AuthorID              int64 `json:",string"`
+	AuthorUsernameDiscrim string
+
+	Reason string
+	// Duration of the punishment in minutes, 0 if permanent or not applicable
+	DurationMinutes int
+	LogsLink        string
+}
+
+func (c *CaseModel) TableName() string {
+	return "moderation_cases"
+}
+
+// CaseCounterModel keeps track of the last case number used in a server
+type CaseCounterModel struct {
+	GuildID        int64 `gorm:"primary_key;auto_increment:false"`
+	LastCaseNumber int64
+}
+
+func (c *CaseCounterModel) TableName() string {
+	return "moderation_case_counters"
+}
+
+// CreateCase records a moderation action, author can be nil if unknown
+func CreateCase(guildID int64, action string, author *discordgo.User, target *discordgo.User, reason string, duration time.Duration, logsLink string) (*CaseModel, error) {
+	c := &CaseModel{
+		GuildID:               guildID,
+		Action:                action,
+		UserID:                target.ID,
+		UserUsernameDiscrim:   target.Username + "#" + target.Discriminator,
+		AuthorUsernameDiscrim: "Unknown",
+		Reason:                reason,
+		DurationMinutes:       int(duration.Minutes()),
+		LogsLink:              logsLink,
+	}
+
+	if author != nil {
+		c.AuthorID = author.ID
+		c.AuthorUsernameDiscrim = author.Username + "#" + author.Discriminator
+	}
+
+	tx := common.GORM.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	const q = `INSERT INTO moderation_case_counters (guild_id, last_case_number) VALUES (?, 1)
+ON CONFLICT (guild_id) DO UPDATE SET last_case_number = moderation_case_counters.last_case_number + 1
+RETURNING last_case_number`
+
+	err := tx.Raw(q, guildID).Row().Scan(&c.CaseNumber)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Create(c).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	return c, err
+}
+
+// logCase creates a case and logs the error if it fails, the action itself has already been performed at that point so there's no point in failing it
+func logCase(guildID int64, action string, author *discordgo.User, target *discordgo.User, reason string, duration time.Duration, logsLink string) *CaseModel {
+	c, err := CreateCase(guildID, action, author, target, reason, duration, logsLink)
+	if err != nil {
+		logger.WithError(err).WithField("guild", guildID).Error("failed creating moderation case")
+		return nil
+	}
+
+	return c
+}
+
+// CaseCmdRoles returns all the roles that can use any of the moderation commands, used for the case commands
+func (c *Config) CaseCmdRoles() []int64 {
+	roles := make([]int64, 0, len(c.BanCmdRoles)+len(c.KickCmdRoles)+len(c.MuteCmdRoles)+len(c.WarnCmdRoles))
+	roles = append(roles, c.BanCmdRoles...)
+	roles = append(roles, c.KickCmdRoles...)
+	roles = append(roles, c.MuteCmdRoles...)
+	roles = append(roles, c.WarnCmdRoles...)
+	return roles
+}
+
+func caseEmbed(c *CaseModel) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:     fmt.Sprintf("Case #%d: %s", c.CaseNumber, c.Action),
+		Timestamp: c.CreatedAt.Format(time.RFC3339),
+		Fields: []*discordgo.MessageEmbedField{
+			&discordgo.MessageEmbedField{Name: "User", Value: fmt.Sprintf("%s (%d)", c.UserUsernameDiscrim, c.UserID), Inline: true},
+			&discordgo.MessageEmbedField{Name: "Moderator", Value: fmt.Sprintf("%s (%d)", c.AuthorUsernameDiscrim, c.AuthorID), Inline: true},
+		},
+	}
+
+	if c.DurationMinutes > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Duration",
+			Value:  common.HumanizeDuration(common.DurationPrecisionMinutes, time.Duration(c.DurationMinutes)*time.Minute),
+			Inline: true,
+		})
+	}
+
+	reason := c.Reason
+	if reason == "" {
+		reason = "(no reason specified)"
+	}
+	embed.Description = "**Reason:** " + reason
+
+	if c.LogsLink != "" {
+		embed.Description += "\n[Logs](" + c.LogsLink + ")"
+	}
+
+	return embed
+}
+
+const casesPerPage = 15
+
+var CaseCommands = []*commands.YAGCommand{
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Case",
+		Description:   "Shows a moderation case",
+		RequiredArgs:  1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Number", Type: dcmd.Int},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			config, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionKickMembers, config.CaseCmdRoles(), true)
+			if err != nil {
+				return nil, err
+			}
+
+			var c CaseModel
+			err = common.GORM.Where("guild_id = ? AND case_number = ?", parsed.GS.ID, parsed.Args[0].Int()).First(&c).Error
+			if err != nil {
+				if err == gorm.ErrRecordNotFound {
+					return "Couldn't find that case", nil
+				}
+				return nil, err
+			}
+
+			return caseEmbed(&c), nil
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled:   true,
+		CmdCategory:     commands.CategoryModeration,
+		Name:            "Cases",
+		Description:     "Lists and searches moderation cases, optionally only for a user",
+		LongDescription: "Filter by moderator with -mod, action with -a (e.g banned, muted, warned) and by text in the reason with -s.",
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID, Default: 0},
+		},
+		ArgSwitches: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Switch: "mod", Name: "Moderator", Type: dcmd.UserID},
+			&dcmd.ArgDef{Switch: "a", Name: "Action", Type: dcmd.String},
+			&dcmd.ArgDef{Switch: "s", Name: "Search", Type: dcmd.String},
+			&dcmd.ArgDef{Switch: "p", Name: "Page", Type: &dcmd.IntArg{Min: 1, Max: 10000}, Default: 1},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			config, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionKickMembers, config.CaseCmdRoles(), true)
+			if err != nil {
+				return nil, err
+			}
+
+			q := common.GORM.Where("guild_id = ?", parsed.GS.ID)
+			if userID := parsed.Args[0].Int64(); userID != 0 {
+				q = q.Where("user_id = ?", userID)
+			}
+			if parsed.Switches["mod"].Value != nil {
+				q = q.Where("author_id = ?", parsed.Switches["mod"].Int64())
+			}
+			if parsed.Switches["a"].Value != nil {
+				q = q.Where("lower(action) = lower(?)", strings.TrimSpace(parsed.Switches["a"].Str()))
+			}
+			if parsed.Switches["s"].Value != nil {
+				q = q.Where("strpos(lower(reason), lower(?)) > 0", parsed.Switches["s"].Str())
+			}
+
+			page := parsed.Switches["p"].Int()
+
+			var result []*CaseModel
+			err = q.Order("case_number desc").Offset((page - 1) * casesPerPage).Limit(casesPerPage).Find(&result).Error
+			if err != nil && err != gorm.ErrRecordNotFound {
+				return nil, err
+			}
+
+			if len(result) < 1 {
+				return "No cases found", nil
+			}
+
+			var out strings.Builder
+			for _, c := range result {
+				reason := common.CutStringShort(c.Reason, 100)
+				if reason == "" {
+					reason = "(no reason specified)"
+				}
+
+				fmt.Fprintf(&out, "#%d: `%s` **%s** %s (%d) by %s - %s\n", c.CaseNumber, c.CreatedAt.UTC().Format("2006-01-02 15:04"), c.Action,
+					c.UserUsernameDiscrim, c.UserID, c.AuthorUsernameDiscrim, reason)
+			}
+
+			if len(result) >= casesPerPage {
+				fmt.Fprintf(&out, "Use `-p %d` to see the next page", page+1)
+			}
+
+			return out.String(), nil
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "EditCase",
+		Description:   "Edits the reason of a moderation case",
+		RequiredArgs:  2,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Number", Type: dcmd.Int},
+			&dcmd.ArgDef{Name: "Reason", Type: dcmd.String},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			config, _, err := MBaseCmd(parsed, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionKickMembers, config.CaseCmdRoles(), true)
+			if err != nil {
+				return nil, err
+			}
+
+			rows := common.GORM.Model(CaseModel{}).Where("guild_id = ? AND case_number = ?", parsed.GS.ID, parsed.Args[0].Int()).Update(
+				"reason", fmt.Sprintf("%s (updated by %s#%s (%d))", parsed.Args[1].Str(), parsed.Msg.Author.Username, parsed.Msg.Author.Discriminator, parsed.Msg.Author.ID)).RowsAffected
+
+			if rows < 1 {
+				return "Failed updating, most likely couldn't find the case", nil
+			}
+
+			return "👌", nil
+		},
+	},
+}
@@ -18,6 +18,13 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	// MaxCleanMessages is the max number of messages the clean command can delete at a time
+	MaxCleanMessages = 1000
+	// MaxCleanFetch is how far back in history the clean command looks when filtering
+	MaxCleanFetch = 2000
+)
+
 func MBaseCmd(cmdData *dcmd.Data, targetID int64) (config *Config, targetUser *discordgo.User, err error) {
 	config, err = GetConfig(cmdData.GS.ID)
 	if err != nil {
@@ -308,21 +315,28 @@ var ModerationCommands = []*commands.YAGCommand{
 		},
 	},
 	&commands.YAGCommand{
-		CustomEnabled:   true,
-		CmdCategory:     commands.CategoryModeration,
-		Name:            "Clean",
-		Description:     "Delete the last number of messages from chat, optionally filtering by user, max age and regex.",
-		LongDescription: "Specify a regex with \"-r regex_here\" and max age with \"-ma 1h10m\"\nNote: Will only look in the last 1k messages",
-		Aliases:         []string{"clear", "cl"},
-		RequiredArgs:    1,
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Clean",
+		Description:   "Delete the last number of messages from chat, optionally filtering by user, max age, regex and content type.",
+		LongDescription: "Specify a regex with \"-r regex_here\" and max age with \"-ma 1h10m\"\n" +
+			"Only delete messages from bots with -bots, with attachments with -attachments, with links with -links, with embeds with -embeds and containing some text with -contains\n" +
+			"Note: Will only look in the last 2k messages",
+		Aliases:      []string{"clear", "cl"},
+		RequiredArgs: 1,
 		Arguments: []*dcmd.ArgDef{
-			&dcmd.ArgDef{Name: "Num", Type: &dcmd.IntArg{Min: 1, Max: 100}},
+			&dcmd.ArgDef{Name: "Num", Type: &dcmd.IntArg{Min: 1, Max: MaxCleanMessages}},
 			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID, Default: 0},
 		},
 		ArgSwitches: []*dcmd.ArgDef{
 			&dcmd.ArgDef{Switch: "r", Name: "Regex", Type: dcmd.String},
 			&dcmd.ArgDef{Switch: "ma", Default: time.Duration(0), Name: "Max age", Type: &commands.DurationArg{}},
 			&dcmd.ArgDef{Switch: "i", Name: "Regex case insensitive"},
+			&dcmd.ArgDef{Switch: "contains", Name: "Containing text", Type: dcmd.String},
+			&dcmd.ArgDef{Switch: "bots", Name: "Only bots"},
+			&dcmd.ArgDef{Switch: "attachments", Name: "Only messages with attachments"},
+			&dcmd.ArgDef{Switch: "links", Name: "Only messages with links"},
+			&dcmd.ArgDef{Switch: "embeds", Name: "Only messages with embeds"},
 		},
 		ArgumentCombos: [][]int{[]int{0}, []int{0, 1}, []int{1, 0}},
 		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
@@ -336,15 +350,26 @@ var ModerationCommands = []*commands.YAGCommand{
 				return nil, err
 			}
 
-			userFilter := parsed.Args[1].Int64()
+			filter := &DeleteFilter{
+				User:            parsed.Args[1].Int64(),
+				MaxAge:          parsed.Switches["ma"].Value.(time.Duration),
+				BotsOnly:        parsed.Switches["bots"].Value != nil && parsed.Switches["bots"].Value.(bool),
+				AttachmentsOnly: parsed.Switches["attachments"].Value != nil && parsed.Switches["attachments"].Value.(bool),
+				LinksOnly:       parsed.Switches["links"].Value != nil && parsed.Switches["links"].Value.(bool),
+				EmbedsOnly:      parsed.Switches["embeds"].Value != nil && parsed.Switches["embeds"].Value.(bool),
+			}
+
+			if parsed.Switches["contains"].Value != nil {
+				filter.Contains = parsed.Switches["contains"].Str()
+			}
 
 			num := parsed.Args[0].Int()
-			if userFilter == 0 || userFilter == parsed.Msg.Author.ID {
+			if filter.User == 0 || filter.User == parsed.Msg.Author.ID {
 				num++ // Automatically include our own message
 			}
 
-			if num > 100 {
-				num = 100
+			if num > MaxCleanMessages {
+				num = MaxCleanMessages
 			}
 
 			if num < 1 {
@@ -354,41 +379,31 @@ var ModerationCommands = []*commands.YAGCommand{
 				return errors.New("Can't delete nothing"), nil
 			}
 
-			filtered := false
-
 			// Check if we should regex match this
-			re := ""
 			if parsed.Switches["r"].Value != nil {
-				filtered = true
-				re = parsed.Switches["r"].Str()
+				filter.Regex = parsed.Switches["r"].Str()
 
 				// Add the case insensitive flag if needed
 				if parsed.Switches["i"].Value != nil && parsed.Switches["i"].Value.(bool) {
-					if !strings.HasPrefix(re, "(?i)") {
-						re = "(?i)" + re
+					if !strings.HasPrefix(filter.Regex, "(?i)") {
+						filter.Regex = "(?i)" + filter.Regex
 					}
 				}
 			}
 
-			// Check if we have a max age
-			ma := parsed.Switches["ma"].Value.(time.Duration)
-			if ma != 0 {
-				filtered = true
-			}
-
 			limitFetch := num
-			if userFilter != 0 || filtered {
+			if filter.User != 0 || filter.Filtered() {
 				limitFetch = num * 50 // Maybe just change to full fetch?
 			}
 
-			if limitFetch > 1000 {
-				limitFetch = 1000
+			if limitFetch > MaxCleanFetch {
+				limitFetch = MaxCleanFetch
 			}
 
 			// Wait a second so the client dosen't gltich out
 			time.Sleep(time.Second)
 
-			numDeleted, err := AdvancedDeleteMessages(parsed.Msg.ChannelID, userFilter, re, ma, num, limitFetch)
+			numDeleted, err := AdvancedDeleteMessages(parsed.Msg.ChannelID, filter, num, limitFetch)
 
 			return dcmd.NewTemporaryResponse(time.Second*5, fmt.Sprintf("Deleted %d message(s)! :')", numDeleted), true), err
 		},
@@ -445,12 +460,16 @@ var ModerationCommands = []*commands.YAGCommand{
 		CustomEnabled: true,
 		CmdCategory:   commands.CategoryModeration,
 		Name:          "Warn",
-		Description:   "Warns a user, warnings are saved using the bot. Use -warnings to view them.",
+		Description:   "Warns a user, warnings are saved using the bot. Use -warnings to view them. Specify when the warning expires with -d, or use -p to make it never expire",
 		RequiredArgs:  2,
 		Arguments: []*dcmd.ArgDef{
 			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
 			&dcmd.ArgDef{Name: "Reason", Type: dcmd.String},
 		},
+		ArgSwitches: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Switch: "d", Default: time.Duration(0), Name: "Expires after", Type: &commands.DurationArg{}},
+			&dcmd.ArgDef{Switch: "p", Name: "Never expires"},
+		},
 		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
 			config, target, err := MBaseCmd(parsed, parsed.Args[0].Int64())
 			if err != nil {
@@ -461,7 +480,16 @@ var ModerationCommands = []*commands.YAGCommand{
 				return nil, err
 			}
 
-			err = WarnUser(config, parsed.GS.ID, parsed.CS.ID, parsed.Msg.Author, target, parsed.Args[1].Str())
+			// the servers default expiry only applies to warnings given with this command
+			expiry := parsed.Switches["d"].Value.(time.Duration)
+			permanent := parsed.Switches["p"].Value != nil && parsed.Switches["p"].Value.(bool)
+			if permanent {
+				expiry = 0
+			} else if expiry == 0 && config.WarnExpiryDays > 0 {
+				expiry = time.Hour * 24 * time.Duration(config.WarnExpiryDays)
+			}
+
+			err = WarnUserWithExpiry(config, parsed.GS.ID, parsed.CS.ID, parsed.Msg.Author, target, parsed.Args[1].Str(), expiry)
 			if err != nil {
 				return nil, err
 			}
@@ -473,12 +501,15 @@ var ModerationCommands = []*commands.YAGCommand{
 		CustomEnabled: true,
 		CmdCategory:   commands.CategoryModeration,
 		Name:          "Warnings",
-		Description:   "Lists warning of a user.",
+		Description:   "Lists warning of a user, use -a to include expired warnings.",
 		Aliases:       []string{"Warns"},
 		RequiredArgs:  1,
 		Arguments: []*dcmd.ArgDef{
 			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
 		},
+		ArgSwitches: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Switch: "a", Name: "Include expired"},
+		},
 		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
 			config, _, err := MBaseCmd(parsed, 0)
 			if err != nil {
@@ -492,19 +523,32 @@ var ModerationCommands = []*commands.YAGCommand{
 
 			userID := parsed.Args[0].Int64()
 
+			q := common.GORM.Where("user_id = ? AND guild_id = ?", userID, parsed.GS.ID)
+			if parsed.Switches["a"].Value == nil || !parsed.Switches["a"].Value.(bool) {
+				q = q.Where(WarningNotExpiredQuery)
+			}
+
 			var result []*WarningModel
-			err = common.GORM.Where("user_id = ? AND guild_id = ?", userID, parsed.GS.ID).Order("id desc").Find(&result).Error
+			err = q.Order("id desc").Find(&result).Error
 			if err != nil && err != gorm.ErrRecordNotFound {
 				return nil, err
 			}
 
 			if len(result) < 1 {
-				return "This user has not received any warnings", nil
+				return "This user has no active warnings", nil
 			}
 
 			out := ""
 			for _, entry := range result {
-				out += fmt.Sprintf("#%d: `%20s` **%s** (%13s) - **%s**\n", entry.ID, entry.CreatedAt.Format(time.RFC822), entry.AuthorUsernameDiscrim, entry.AuthorID, entry.Message)
+				out += fmt.Sprintf("#%d: `%20s` **%s** (%13s) - **%s**", entry.ID, entry.CreatedAt.Format(time.RFC822), entry.AuthorUsernameDiscrim, entry.AuthorID, entry.Message)
+				if entry.ExpiresAt != nil {
+					if time.Until(*entry.ExpiresAt) > 0 {
+						out += " (expires in " + common.HumanizeDuration(common.DurationPrecisionMinutes, time.Until(*entry.ExpiresAt)) + ")"
+					} else {
+						out += " (expired)"
+					}
+				}
+				out += "\n"
 				if entry.LogsLink != "" {
 					out += "^logs: <" + entry.LogsLink + ">\n"
 				}
@@ -665,6 +709,8 @@ var ModerationCommands = []*commands.YAGCommand{
 				}
 			}
 
+			logCase(parsed.GS.ID, ActionGaveRole, parsed.Msg.Author, target, "Role: "+role.Name, dur, "")
+
 			action := MAGiveRole
 			action.Prefix = "Gave the role " + role.Name + " to "
 			if config.GiveRoleCmdModlog && config.IntActionChannel() != 0 {
@@ -726,6 +772,8 @@ var ModerationCommands = []*commands.YAGCommand{
 			// cancel the event to remove the role
 			scheduledevents2.CancelRemoveRole(parsed.Context(), parsed.GS.ID, parsed.Msg.Author.ID, role.ID)
 
+			logCase(parsed.GS.ID, ActionRemovedRole, parsed.Msg.Author, target, "Role: "+role.Name, 0, "")
+
 			action := MARemoveRole
 			action.Prefix = "Removed the role " + role.Name + " from "
 			if config.GiveRoleCmdModlog && config.IntActionChannel() != 0 {
@@ -737,12 +785,32 @@ var ModerationCommands = []*commands.YAGCommand{
 	},
 }
 
-func AdvancedDeleteMessages(channelID int64, filterUser int64, regex string, maxAge time.Duration, deleteNum, fetchNum int) (int, error) {
+// DeleteFilter selects which messages AdvancedDeleteMessages deletes, zero values are ignored
+type DeleteFilter struct {
+	User     int64
+	Regex    string
+	MaxAge   time.Duration
+	Contains string
+
+	BotsOnly        bool
+	AttachmentsOnly bool
+	LinksOnly       bool
+	EmbedsOnly      bool
+}
+
+// Filtered returns true if any filter other than the user is set
+func (f *DeleteFilter) Filtered() bool {
+	return f.Regex != "" || f.MaxAge != 0 || f.Contains != "" || f.BotsOnly || f.AttachmentsOnly || f.LinksOnly || f.EmbedsOnly
+}
+
+// AdvancedDeleteMessages deletes up to deleteNum messages matching the filter within the last fetchNum messages,
+// more than 100 messages are deleted in batches through the delete queue
+func AdvancedDeleteMessages(channelID int64, filter *DeleteFilter, deleteNum, fetchNum int) (int, error) {
 	var compiledRegex *regexp.Regexp
-	if regex != "" {
+	if filter.Regex != "" {
 		// Start by compiling the regex
 		var err error
-		compiledRegex, err = regexp.Compile(regex)
+		compiledRegex, err = regexp.Compile(filter.Regex)
 		if err != nil {
 			return 0, err
 		}
@@ -756,7 +824,7 @@ func AdvancedDeleteMessages(channelID int64, filterUser int64, regex string, max
 	toDelete := make([]int64, 0)
 	now := time.Now()
 	for i := len(msgs) - 1; i >= 0; i-- {
-		if filterUser != 0 && msgs[i].Author.ID != filterUser {
+		if filter.User != 0 && msgs[i].Author.ID != filter.User {
 			continue
 		}
 
@@ -773,27 +841,45 @@ func AdvancedDeleteMessages(channelID int64, filterUser int64, regex string, max
 		}
 
 		// Check max age
-		if maxAge != 0 && now.Sub(msgs[i].ParsedCreated) > maxAge {
+		if filter.MaxAge != 0 && now.Sub(msgs[i].ParsedCreated) > filter.MaxAge {
+			continue
+		}
+
+		if filter.Contains != "" && !strings.Contains(strings.ToLower(msgs[i].Content), strings.ToLower(filter.Contains)) {
+			continue
+		}
+
+		if filter.BotsOnly && !msgs[i].Author.Bot {
+			continue
+		}
+
+		if filter.AttachmentsOnly && len(msgs[i].Attachments) < 1 {
+			continue
+		}
+
+		if filter.LinksOnly && !common.LinkRegex.MatchString(msgs[i].Content) {
+			continue
+		}
+
+		if filter.EmbedsOnly && len(msgs[i].Embeds) < 1 {
 			continue
 		}
 
 		toDelete = append(toDelete, msgs[i].ID)
 		//log.Println("Deleting", msgs[i].ContentWithMentionsReplaced())
-		if len(toDelete) >= deleteNum || len(toDelete) >= 100 {
+		if len(toDelete) >= deleteNum {
 			break
 		}
 	}
 
-	if len(toDelete) < 1 {
-		return 0, nil
-	}
-
 	if len(toDelete) < 1 {
 		return 0, nil
 	} else if len(toDelete) == 1 {
 		err = common.BotSession.ChannelMessageDelete(channelID, toDelete[0])
-	} else {
+	} else if len(toDelete) <= 100 {
 		err = common.BotSession.ChannelMessagesBulkDelete(channelID, toDelete)
+	} else {
+		bot.MessageDeleteQueue.DeleteMessages(channelID, toDelete...)
 	}
 
 	return len(toDelete), err
@@ -0,0 +1,243 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jonas747/dcmd"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/commands"
+	"github.com/jonas747/yagpdb/common"
+	"github.com/jonas747/yagpdb/common/scheduledevents2"
+	seventsmodels "github.com/jonas747/yagpdb/common/scheduledevents2/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// LockedChannelModel stores the @everyone overwrite a channel had before it was locked, so it can be restored exactly
+type LockedChannelModel struct {
+	ChannelID int64 `gorm:"primary_key;auto_increment:false"`
+	GuildID   int64 `gorm:"index"`
+
+	// False if there was no @everyone overwrite, in which case it's deleted when unlocking
+	HadOverwrite bool
+	Allow        int
+	Deny         int
+
+	CreatedAt time.Time
+}
+
+func (l *LockedChannelModel) TableName() string {
+	return "moderation_locked_channels"
+}
+
+type ScheduledUnlockData struct {
+	ChannelID int64 `json:"channel_id"`
+}
+
+// LockChannel denies @everyone the send messages permission in the channel, returns false if it was already locked
+func LockChannel(guildID int64, channel *discordgo.Channel) (bool, error) {
+	var existing LockedChannelModel
+	err := common.GORM.Where("channel_id = ?", channel.ID).First(&existing).Error
+	if err == nil {
+		return false, nil
+	} else if err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+
+	model := &LockedChannelModel{
+		ChannelID: channel.ID,
+		GuildID:   guildID,
+	}
+
+	// Check for existing override
+	for _, v := range channel.PermissionOverwrites {
+		if v.Type == "role" && v.ID == guildID {
+			model.HadOverwrite = true
+			model.Allow = v.Allow
+			model.Deny = v.Deny
+			break
+		}
+	}
+
+	allows := model.Allow &^ discordgo.PermissionSendMessages
+	denies := model.Deny | discordgo.PermissionSendMessages
+
+	err = common.GORM.Create(model).Error
+	if err != nil {
+		return false, err
+	}
+
+	err = common.BotSession.ChannelPermissionSet(channel.ID, guildID, "role", allows, denies)
+	if err != nil {
+		common.GORM.Delete(model)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UnlockChannel restores the @everyone overwrite the channel had before it was locked, returns false if it wasn't locked
+func UnlockChannel(guildID, channelID int64) (bool, error) {
+	var model LockedChannelModel
+	err := common.GORM.Where("guild_id = ? AND channel_id = ?", guildID, channelID).First(&model).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if model.HadOverwrite {
+		err = common.BotSession.ChannelPermissionSet(channelID, guildID, "role", model.Allow, model.Deny)
+	} else {
+		err = common.BotSession.ChannelPermissionDelete(channelID, guildID)
+	}
+
+	if err != nil && !common.IsDiscordErr(err, discordgo.ErrCodeUnknownChannel) {
+		return false, err
+	}
+
+	_, err = seventsmodels.ScheduledEvents(qm.Where("event_name='moderation_unlock_channel' AND guild_id = ? AND (data->>'channel_id')::bigint = ?", guildID, channelID)).DeleteAll(context.Background(), common.PQ)
+	common.LogIgnoreError(err, "[moderation] failed clearing unlock events", nil)
+
+	err = common.GORM.Delete(&model).Error
+	return true, err
+}
+
+// lockTargetChannels returns the channel, or if it's a category the category and the text channels in it
+func lockTargetChannels(gs *dstate.GuildState, cs *dstate.ChannelState) []*discordgo.Channel {
+	gs.RLock()
+	defer gs.RUnlock()
+
+	result := []*discordgo.Channel{cs.DGoCopy()}
+	if cs.Type != discordgo.ChannelTypeGuildCategory {
+		return result
+	}
+
+	for _, v := range gs.Channels {
+		if v.ParentID == cs.ID && v.Type == discordgo.ChannelTypeGuildText {
+			result = append(result, v.DGoCopy())
+		}
+	}
+
+	return result
+}
+
+func handleScheduledUnlock(evt *seventsmodels.ScheduledEvent, data interface{}) (retry bool, err error) {
+	unlockData := data.(*ScheduledUnlockData)
+
+	_, err = UnlockChannel(evt.GuildID, unlockData.ChannelID)
+	if err != nil {
+		return scheduledevents2.CheckDiscordErrRetry(err), err
+	}
+
+	return false, nil
+}
+
+var LockdownCommands = []*commands.YAGCommand{
+	&commands.YAGCommand{
+		CustomEnabled:   true,
+		CmdCategory:     commands.CategoryModeration,
+		Name:            "Lock",
+		Description:     "Denies everyone the send messages permission in a channel, or all text channels in a category, optionally for a duration (-d)",
+		LongDescription: "Defaults to the current channel. `Unlock` restores the permissions to exactly what they were before.",
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Channel", Type: dcmd.Channel},
+		},
+		ArgSwitches: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Switch: "d", Default: time.Duration(0), Name: "Duration", Type: &commands.DurationArg{}},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			_, err := MBaseCmdSecond(parsed, "", true, discordgo.PermissionManageChannels, nil, true)
+			if err != nil {
+				return nil, err
+			}
+
+			cs := parsed.CS
+			if parsed.Args[0].Value != nil {
+				cs = parsed.Args[0].Value.(*dstate.ChannelState)
+			}
+
+			duration := parsed.Switches["d"].Value.(time.Duration)
+
+			locked := 0
+			for _, channel := range lockTargetChannels(parsed.GS, cs) {
+				if !bot.BotProbablyHasPermission(parsed.GS.ID, channel.ID, discordgo.PermissionManageRoles) {
+					continue
+				}
+
+				didLock, err := LockChannel(parsed.GS.ID, channel)
+				if err != nil {
+					return nil, err
+				}
+
+				if !didLock {
+					continue
+				}
+
+				locked++
+				if duration > 0 {
+					err = scheduledevents2.ScheduleEvent("moderation_unlock_channel", parsed.GS.ID, time.Now().Add(duration), &ScheduledUnlockData{
+						ChannelID: channel.ID,
+					})
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			if locked < 1 {
+				return "Nothing to lock, it's either already locked or i don't have permissions to manage it", nil
+			}
+
+			resp := fmt.Sprintf("🔒 Locked %d channel(s)", locked)
+			if duration > 0 {
+				resp += " for " + common.HumanizeDuration(common.DurationPrecisionMinutes, duration)
+			}
+
+			return resp, nil
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Unlock",
+		Description:   "Unlocks a channel or category locked with the Lock command, restoring the previous permissions",
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Channel", Type: dcmd.Channel},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			_, err := MBaseCmdSecond(parsed, "", true, discordgo.PermissionManageChannels, nil, true)
+			if err != nil {
+				return nil, err
+			}
+
+			cs := parsed.CS
+			if parsed.Args[0].Value != nil {
+				cs = parsed.Args[0].Value.(*dstate.ChannelState)
+			}
+
+			unlocked := 0
+			for _, channel := range lockTargetChannels(parsed.GS, cs) {
+				didUnlock, err := UnlockChannel(parsed.GS.ID, channel.ID)
+				if err != nil {
+					return nil, err
+				}
+
+				if didUnlock {
+					unlocked++
+				}
+			}
+
+			if unlocked < 1 {
+				return "Nothing to unlock", nil
+			}
+
+			return fmt.Sprintf("🔓 Unlocked %d channel(s)", unlocked), nil
+		},
+	},
+}
@@ -0,0 +1,428 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonas747/dcmd"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/dstate"
+	"github.com/jonas747/retryableredis"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/commands"
+	"github.com/jonas747/yagpdb/common"
+)
+
+const (
+	MaxMassbanUsers = 1000
+
+	// how long a massban preview can be confirmed for
+	massbanConfirmSeconds = 300
+)
+
+func RedisKeyPendingMassban(guildID, authorID int64) string {
+	return "moderation_pending_massban:" + discordgo.StrID(guildID) + ":" + discordgo.StrID(authorID)
+}
+
+// only one massban can run at a time per server
+func RedisKeyMassbanRunning(guildID int64) string {
+	return "moderation_massban_running:" + discordgo.StrID(guildID)
+}
+
+// PendingMassban is a previewed massban waiting to be confirmed
+type PendingMassban struct {
+	UserIDs  []int64
+	Kick     bool
+	Reason   string
+	Duration time.Duration
+}
+
+// MassbanCriteria selects members from the guild state, zero values are ignored
+type MassbanCriteria struct {
+	JoinedWithin  time.Duration
+	MaxAccountAge time.Duration
+	UsernameRegex *regexp.Regexp
+}
+
+func (c *MassbanCriteria) empty() bool {
+	return c.JoinedWithin == 0 && c.MaxAccountAge == 0 && c.UsernameRegex == nil
+}
+
+// FindMassbanTargets returns the members matching all the criteria, excluding bots and members not below the author
+func FindMassbanTargets(gs *dstate.GuildState, author *dstate.MemberState, criteria *MassbanCriteria) []int64 {
+	gs.RLock()
+	defer gs.RUnlock()
+
+	now := time.Now()
+
+	result := make([]int64, 0)
+	for _, ms := range gs.Members {
+		if !ms.MemberSet || ms.Bot || ms.ID == author.ID {
+			continue
+		}
+
+		if criteria.JoinedWithin > 0 && now.Sub(ms.JoinedAt) > criteria.JoinedWithin {
+			continue
+		}
+
+		if criteria.MaxAccountAge > 0 && now.Sub(bot.SnowflakeToTime(ms.ID)) > criteria.MaxAccountAge {
+			continue
+		}
+
+		if criteria.UsernameRegex != nil && !criteria.UsernameRegex.MatchString(ms.Username) {
+			continue
+		}
+
+		if !bot.IsMemberAbove(gs, author, ms) {
+			continue
+		}
+
+		result = append(result, ms.ID)
+	}
+
+	return result
+}
+
+// filterMassbanTargets removes the author, the bot, the owner and the members not ranked below the author from the targets.
+// Users that aren't members of the server are kept, as they can still be banned by ID.
+func filterMassbanTargets(gs *dstate.GuildState, author *dstate.MemberState, targets []int64) (result []int64, skipped int) {
+	members, _ := bot.GetMembers(gs.ID, targets...)
+
+	gs.RLock()
+	ownerID := gs.Guild.OwnerID
+	notBelow := make([]int64, 0)
+	for _, ms := range members {
+		if !bot.IsMemberAbove(gs, author, ms) {
+			notBelow = append(notBelow, ms.ID)
+		}
+	}
+	gs.RUnlock()
+
+	result = make([]int64, 0, len(targets))
+	for _, v := range targets {
+		if v == author.ID || v == common.BotUser.ID || v == ownerID || common.ContainsInt64Slice(notBelow, v) {
+			skipped++
+			continue
+		}
+
+		result = append(result, v)
+	}
+
+	return result, skipped
+}
+
+// parseMassbanIDs parses a list of user ids or mentions separated by spaces, commas or new lines
+func parseMassbanIDs(input string) ([]int64, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\n'
+	})
+
+	result := make([]int64, 0, len(fields))
+	for _, v := range fields {
+		v = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(v, "<@"), "!"), ">")
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, commands.NewPublicErrorF("Invalid user ID: `%s`", v)
+		}
+
+		if !common.ContainsInt64Slice(result, parsed) {
+			result = append(result, parsed)
+		}
+	}
+
+	return result, nil
+}
+
+var MassbanCommand = &commands.YAGCommand{
+	CustomEnabled: true,
+	CmdCategory:   commands.CategoryModeration,
+	Name:          "Massban",
+	Description:   "Bans (or kicks with -kick) many users at once, either by ID or by criteria. Shows a preview that has to be confirmed with -confirm",
+	LongDescription: "Specify user IDs separated by spaces, or select members with -joined (joined within duration), -age (account younger than duration) and -name (username regex).\n" +
+		"Specify a reason with -r and a ban duration with -d. Run `massban -confirm` within 5 minutes of the preview to perform it.",
+	Arguments: []*dcmd.ArgDef{
+		&dcmd.ArgDef{Name: "UserIDs", Type: dcmd.String},
+	},
+	ArgSwitches: []*dcmd.ArgDef{
+		&dcmd.ArgDef{Switch: "joined", Default: time.Duration(0), Name: "Joined within", Type: &commands.DurationArg{}},
+		&dcmd.ArgDef{Switch: "age", Default: time.Duration(0), Name: "Max account age", Type: &commands.DurationArg{}},
+		&dcmd.ArgDef{Switch: "name", Name: "Username regex", Type: dcmd.String},
+		&dcmd.ArgDef{Switch: "r", Name: "Reason", Type: dcmd.String},
+		&dcmd.ArgDef{Switch: "d", Default: time.Duration(0), Name: "Ban duration", Type: &commands.DurationArg{}},
+		&dcmd.ArgDef{Switch: "kick", Name: "Kick instead of ban"},
+		&dcmd.ArgDef{Switch: "confirm", Name: "Confirm the previewed massban"},
+	},
+	RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+		config, _, err := MBaseCmd(parsed, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		redisKey := RedisKeyPendingMassban(parsed.GS.ID, parsed.Msg.Author.ID)
+
+		if parsed.Switches["confirm"].Value != nil && parsed.Switches["confirm"].Value.(bool) {
+			var pending *PendingMassban
+			err = common.GetRedisJson(redisKey, &pending)
+			if err != nil {
+				return nil, err
+			}
+
+			if pending == nil {
+				return "No pending massban, or it expired. Run the massban command without -confirm first.", nil
+			}
+
+			// check the perms and roles again in case they were changed in the meantime
+			err = checkMassbanPerms(parsed, config, pending.Kick)
+			if err != nil {
+				return nil, err
+			}
+
+			pending.UserIDs, _ = filterMassbanTargets(parsed.GS, commands.ContextMS(parsed.Context()), pending.UserIDs)
+			if len(pending.UserIDs) < 1 {
+				common.RedisPool.Do(retryableredis.Cmd(nil, "DEL", redisKey))
+				return "None of the users can be moderated by you anymore", nil
+			}
+
+			locked, err := common.TryLockRedisKey(RedisKeyMassbanRunning(parsed.GS.ID), 60*60)
+			if err != nil {
+				return nil, err
+			}
+
+			if !locked {
+				return "Another massban is already running on this server, wait for it to finish", nil
+			}
+
+			common.RedisPool.Do(retryableredis.Cmd(nil, "DEL", redisKey))
+			go performMassban(config, parsed.GS, parsed.Msg.ChannelID, parsed.Msg.Author, pending)
+
+			return fmt.Sprintf("Started on %d users, a summary will be posted here when it's done", len(pending.UserIDs)), nil
+		}
+
+		kick := parsed.Switches["kick"].Value != nil && parsed.Switches["kick"].Value.(bool)
+		err = checkMassbanPerms(parsed, config, kick)
+		if err != nil {
+			return nil, err
+		}
+
+		reason := parsed.Switch("r").Str()
+		reasonOptional := config.BanReasonOptional
+		if kick {
+			reasonOptional = config.KickReasonOptional
+		}
+		if strings.TrimSpace(reason) == "" {
+			if !reasonOptional {
+				return "A reason has been set to be required for this command by the server admins, specify one with -r", nil
+			}
+			reason = "(No reason specified)"
+		}
+
+		criteria := &MassbanCriteria{
+			JoinedWithin:  parsed.Switch("joined").Value.(time.Duration),
+			MaxAccountAge: parsed.Switch("age").Value.(time.Duration),
+		}
+
+		if parsed.Switch("name").Value != nil {
+			criteria.UsernameRegex, err = regexp.Compile(parsed.Switch("name").Str())
+			if err != nil {
+				return "Invalid username regex: " + err.Error(), nil
+			}
+		}
+
+		var targets []int64
+		skipped := 0
+		if parsed.Args[0].Value != nil {
+			if !criteria.empty() {
+				return "Specify either user IDs or criteria, not both", nil
+			}
+
+			targets, err = parseMassbanIDs(parsed.Args[0].Str())
+			if err != nil {
+				return nil, err
+			}
+
+			if len(targets) > MaxMassbanUsers {
+				return fmt.Sprintf("Specified %d users, the max is %d at a time", len(targets), MaxMassbanUsers), nil
+			}
+
+			targets, skipped = filterMassbanTargets(parsed.GS, commands.ContextMS(parsed.Context()), targets)
+		} else {
+			if criteria.empty() {
+				return "Specify user IDs or at least one of -joined, -age and -name", nil
+			}
+
+			targets = FindMassbanTargets(parsed.GS, commands.ContextMS(parsed.Context()), criteria)
+		}
+
+		if len(targets) < 1 {
+			if skipped > 0 {
+				return "None of the users can be moderated by you", nil
+			}
+			return "No users matched", nil
+		}
+
+		if len(targets) > MaxMassbanUsers {
+			return fmt.Sprintf("Matched %d users, the max is %d at a time", len(targets), MaxMassbanUsers), nil
+		}
+
+		pending := &PendingMassban{
+			UserIDs:  targets,
+			Kick:     kick,
+			Reason:   reason,
+			Duration: parsed.Switch("d").Value.(time.Duration),
+		}
+
+		serialized, err := json.Marshal(pending)
+		if err != nil {
+			return nil, err
+		}
+
+		err = common.RedisPool.Do(retryableredis.FlatCmd(nil, "SET", redisKey, serialized, "EX", massbanConfirmSeconds))
+		if err != nil {
+			return nil, err
+		}
+
+		return massbanPreview(parsed.GS, pending, skipped), nil
+	},
+}
+
+func checkMassbanPerms(parsed *dcmd.Data, config *Config, kick bool) error {
+	var err error
+	if kick {
+		_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionKickMembers, config.KickCmdRoles, config.KickEnabled)
+	} else {
+		_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionBanMembers, config.BanCmdRoles, config.BanEnabled)
+	}
+
+	return err
+}
+
+func massbanPreview(gs *dstate.GuildState, pending *PendingMassban, skipped int) string {
+	action := "ban"
+	if pending.Kick {
+		action = "kick"
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "This will %s **%d** users", action, len(pending.UserIDs))
+	if !pending.Kick && pending.Duration > 0 {
+		out.WriteString(" for `" + common.HumanizeDuration(common.DurationPrecisionMinutes, pending.Duration) + "`")
+	}
+	out.WriteString(", including:\n")
+
+	gs.RLock()
+	for i, v := range pending.UserIDs {
+		if i >= 10 {
+			fmt.Fprintf(&out, "...and %d more\n", len(pending.UserIDs)-i)
+			break
+		}
+
+		name := "unknown user"
+		if ms := gs.Member(false, v); ms != nil && (ms.MemberSet || ms.PresenceSet) {
+			name = ms.Username + "#" + ms.StrDiscriminator()
+		}
+		fmt.Fprintf(&out, "`%d` %s\n", v, name)
+	}
+	gs.RUnlock()
+
+	if skipped > 0 {
+		fmt.Fprintf(&out, "Skipped %d users ranked the same or higher than you, or that can't be moderated.\n", skipped)
+	}
+
+	fmt.Fprintf(&out, "Run `massban -confirm` within %d minutes to %s them.", massbanConfirmSeconds/60, action)
+	return common.EscapeSpecialMentions(out.String())
+}
+
+// performMassban bans or kicks all the users, posting a single summary to the modlog instead of one entry per user.
+// It's ran in the background as it can take a while, the result is sent to channelID when it's done.
+func performMassban(config *Config, gs *dstate.GuildState, channelID int64, author *discordgo.User, pending *PendingMassban) {
+	defer common.UnlockRedisKey(RedisKeyMassbanRunning(gs.ID))
+
+	succeeded := make([]int64, 0, len(pending.UserIDs))
+	failed := 0
+	for _, v := range pending.UserIDs {
+		target := &discordgo.User{
+			ID:            v,
+			Username:      "unknown",
+			Discriminator: "????",
+		}
+
+		if ms, err := bot.GetMember(gs.ID, v); err == nil && ms != nil {
+			target = ms.DGoUser()
+		}
+
+		// the modlog only gets a single summary, but every user still gets their own case
+		// so they show up in the users case history like any other ban
+		var err error
+		if pending.Kick {
+			err = kickUser(config, gs.ID, 0, author, pending.Reason, target, true)
+		} else {
+			err = banUserWithDuration(config, gs.ID, 0, author, pending.Reason, target, pending.Duration, true)
+		}
+
+		if err != nil {
+			logger.WithError(err).WithField("guild", gs.ID).WithField("user", v).Error("failed massbanning user")
+			failed++
+			continue
+		}
+
+		succeeded = append(succeeded, v)
+	}
+
+	action := MABanned
+	if pending.Kick {
+		action = MAKick
+	} else if pending.Duration > 0 {
+		action.Footer = "Expires after: " + common.HumanizeDuration(common.DurationPrecisionMinutes, pending.Duration)
+	}
+
+	if len(succeeded) > 0 {
+		err := createMassModlogEmbed(config.IntActionChannel(), author, action, succeeded, pending.Reason)
+		if err != nil {
+			logger.WithError(err).WithField("guild", gs.ID).Error("failed sending massban modlog summary")
+		}
+	}
+
+	resp := fmt.Sprintf("%s%s %d users", action.Emoji, action.Prefix, len(succeeded))
+	if failed > 0 {
+		resp += fmt.Sprintf(", failed on %d users", failed)
+	}
+
+	_, err := common.BotSession.ChannelMessageSend(channelID, resp)
+	if err != nil {
+		logger.WithError(err).WithField("guild", gs.ID).Error("failed sending massban result")
+	}
+}
+
+func createMassModlogEmbed(channelID int64, author *discordgo.User, action ModlogAction, targets []int64, reason string) error {
+	if channelID == 0 {
+		return nil
+	}
+
+	var ids strings.Builder
+	for _, v := range targets {
+		ids.WriteString(strconv.FormatInt(v, 10) + "\n")
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name:    fmt.Sprintf("%s#%s (ID %d)", author.Username, author.Discriminator, author.ID),
+			IconURL: discordgo.EndpointUserAvatar(author.ID, author.Avatar),
+		},
+		Color: action.Color,
+		Description: fmt.Sprintf("**%s%s %d users**\n📄**Reason:** %s\n```\n%s```",
+			action.Emoji, action.Prefix, len(targets), reason, common.CutStringShort(ids.String(), 1500)),
+	}
+
+	if action.Footer != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{
+			Text: action.Footer,
+		}
+	}
+
+	_, err := common.BotSession.ChannelMessageSendEmbed(channelID, embed)
+	return err
+}
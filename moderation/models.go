@@ -44,7 +44,12 @@ type Config struct {
 	WarnCmdRoles           pq.Int64Array `gorm:"type:bigint[]" valid:"role,true"`
 	WarnIncludeChannelLogs bool
 	WarnSendToModlog       bool
-	WarnMessage            string `valid:"template,5000"`
+	WarnMessage            string            `valid:"template,5000"`
+	WarnThresholds         WarnThresholdList `gorm:"type:jsonb"`
+	WarnExpiryDays         int               `valid:"0,3650"`
+
+	// Notes
+	NoteCmdRoles pq.Int64Array `gorm:"type:bigint[]" valid:"role,true"`
 
 	// Misc
 	CleanEnabled  bool
@@ -57,6 +62,10 @@ type Config struct {
 	GiveRoleCmdEnabled bool
 	GiveRoleCmdModlog  bool
 	GiveRoleCmdRoles   pq.Int64Array `gorm:"type:bigint[]" valid:"role,true"`
+
+	// Appeals
+	AppealsEnabled bool
+	AppealsChannel string `valid:"channel,true"`
 }
 
 func (c *Config) IntMuteRole() (r int64) {
@@ -69,6 +78,11 @@ func (c *Config) IntActionChannel() (r int64) {
 	return
 }
 
+func (c *Config) IntAppealsChannel() (r int64) {
+	r, _ = strconv.ParseInt(c.AppealsChannel, 10, 64)
+	return
+}
+
 func (c *Config) IntReportChannel() (r int64) {
 	r, _ = strconv.ParseInt(c.ReportChannel, 10, 64)
 	return
@@ -104,8 +118,14 @@ type WarningModel struct {
 
 	Message  string
 	LogsLink string
+
+	// Expired warnings don't count towards the warning thresholds and are hidden by default, nil if it never expires
+	ExpiresAt *time.Time
 }
 
+// WarningNotExpiredQuery is a where clause that filters out expired warnings
+const WarningNotExpiredQuery = "(expires_at IS NULL OR expires_at > now())"
+
 func (w *WarningModel) TableName() string {
 	return "moderation_warnings"
 }
@@ -40,6 +40,9 @@ func RedisKeyUnbannedUser(guildID, userID int64) string {
 	return "moderation_unbanned_user:" + discordgo.StrID(guildID) + ":" + discordgo.StrID(userID)
 }
 
+// value of the unbanned user key when the unban was made through UnbanUser, as opposed to timed bans expiring
+const unbanAlreadyLogged = 2
+
 func RedisKeyLockedMute(guildID, userID int64) string {
 	return "moderation_updating_mute:" + discordgo.StrID(guildID) + ":" + discordgo.StrID(userID)
 }
@@ -50,7 +53,13 @@ func RegisterPlugin() {
 	common.RegisterPlugin(plugin)
 
 	configstore.RegisterConfig(configstore.SQL, &Config{})
-	common.GORM.AutoMigrate(&Config{}, &WarningModel{}, &MuteModel{})
+	common.GORM.AutoMigrate(&Config{}, &WarningModel{}, &MuteModel{}, &CaseModel{}, &CaseCounterModel{}, &AppealModel{}, &LockedChannelModel{}, &NoteModel{})
+
+	// gorm can't create partial indexes, this makes sure a user only has one pending appeal at a time
+	err := common.GORM.Exec("CREATE UNIQUE INDEX IF NOT EXISTS moderation_appeals_one_pending_idx ON moderation_appeals(guild_id, user_id) WHERE status = 'pending';").Error
+	if err != nil {
+		logger.WithError(err).Error("failed creating pending appeals index")
+	}
 }
 
 func getConfigIfNotSet(guildID int64, config *Config) (*Config, error) {
@@ -35,6 +35,9 @@ var (
 	MAWarned     = ModlogAction{Prefix: "Warned", Emoji: "⚠", Color: 0xfca253}
 	MAGiveRole   = ModlogAction{Prefix: "", Emoji: "➕", Color: 0x53fcf9}
 	MARemoveRole = ModlogAction{Prefix: "", Emoji: "➖", Color: 0x53fcf9}
+
+	MATempbanUpdated   = ModlogAction{Prefix: "Updated the tempban of", Emoji: "⏲", Color: 0xd64848}
+	MATempbanCancelled = ModlogAction{Prefix: "Made the ban permanent of", Emoji: "🔨", Color: 0xd64848}
 )
 
 func CreateModlogEmbed(channelID int64, author *discordgo.User, action ModlogAction, target *discordgo.User, reason, logLink string) error {
@@ -0,0 +1,143 @@
+package moderation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jonas747/dcmd"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/commands"
+	"github.com/jonas747/yagpdb/common"
+)
+
+// NoteModel is a private note about a user, only visible to moderators. Unlike warnings the user is never notified.
+type NoteModel struct {
+	common.SmallModel
+
+	GuildID int64 `gorm:"index"`
+	UserID  int64 `gorm:"index"`
+
+	AuthorID              int64
+	AuthorUsernameDiscrim string
+
+	Message string
+}
+
+func (n *NoteModel) TableName() string {
+	return "moderation_notes"
+}
+
+const notesPerPage = 15
+
+// noteCmdSecond checks if the author can use the note commands, only the roles set up in NoteCmdRoles and people with manage server can see notes
+func noteCmdSecond(parsed *dcmd.Data) error {
+	config, _, err := MBaseCmd(parsed, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionManageServer, config.NoteCmdRoles, true)
+	return err
+}
+
+var NoteCommands = []*commands.YAGCommand{
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Note",
+		Description:   "Adds a private moderator note to a user, the user is not notified",
+		RequiredArgs:  2,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
+			&dcmd.ArgDef{Name: "Note", Type: dcmd.String},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			err := noteCmdSecond(parsed)
+			if err != nil {
+				return nil, err
+			}
+
+			note := &NoteModel{
+				GuildID:               parsed.GS.ID,
+				UserID:                parsed.Args[0].Int64(),
+				AuthorID:              parsed.Msg.Author.ID,
+				AuthorUsernameDiscrim: parsed.Msg.Author.Username + "#" + parsed.Msg.Author.Discriminator,
+				Message:               common.CutStringShort(parsed.Args[1].Str(), 1000),
+			}
+
+			err = common.GORM.Create(note).Error
+			if err != nil {
+				return nil, err
+			}
+
+			return fmt.Sprintf("Added note #%d 👌", note.ID), nil
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Notes",
+		Description:   "Lists the moderator notes of a user",
+		RequiredArgs:  1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
+		},
+		ArgSwitches: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Switch: "p", Name: "Page", Type: &dcmd.IntArg{Min: 1, Max: 10000}, Default: 1},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			err := noteCmdSecond(parsed)
+			if err != nil {
+				return nil, err
+			}
+
+			page := parsed.Switches["p"].Int()
+
+			var result []*NoteModel
+			err = common.GORM.Where("guild_id = ? AND user_id = ?", parsed.GS.ID, parsed.Args[0].Int64()).Order("id desc").
+				Offset((page - 1) * notesPerPage).Limit(notesPerPage).Find(&result).Error
+			if err != nil && err != gorm.ErrRecordNotFound {
+				return nil, err
+			}
+
+			if len(result) < 1 {
+				return "No notes found", nil
+			}
+
+			var out strings.Builder
+			for _, entry := range result {
+				fmt.Fprintf(&out, "#%d: `%s` **%s** - %s\n", entry.ID, entry.CreatedAt.UTC().Format("2006-01-02 15:04"), entry.AuthorUsernameDiscrim, entry.Message)
+			}
+
+			if len(result) >= notesPerPage {
+				fmt.Fprintf(&out, "Use `-p %d` to see the next page", page+1)
+			}
+
+			return common.EscapeSpecialMentions(out.String()), nil
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "DelNote",
+		Description:   "Deletes a moderator note, id is the first number of each note from the notes command",
+		RequiredArgs:  1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "Id", Type: dcmd.Int},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			err := noteCmdSecond(parsed)
+			if err != nil {
+				return nil, err
+			}
+
+			rows := common.GORM.Where("guild_id = ? AND id = ?", parsed.GS.ID, parsed.Args[0].Int()).Delete(NoteModel{}).RowsAffected
+			if rows < 1 {
+				return "Failed deleting, most likely couldn't find the note", nil
+			}
+
+			return "👌", nil
+		},
+	},
+}
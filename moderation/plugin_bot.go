@@ -37,6 +37,11 @@ var _ bot.ShardMigrationReceiver = (*Plugin)(nil)
 
 func (p *Plugin) AddCommands() {
 	commands.AddRootCommands(ModerationCommands...)
+	commands.AddRootCommands(CaseCommands...)
+	commands.AddRootCommands(MassbanCommand)
+	commands.AddRootCommands(LockdownCommands...)
+	commands.AddRootCommands(NoteCommands...)
+	commands.AddRootCommands(TempbanCommands...)
 }
 
 func (p *Plugin) BotInit() {
@@ -44,11 +49,13 @@ func (p *Plugin) BotInit() {
 	// scheduledevents.RegisterEventHandler("mod_unban", handleUnbanLegacy)
 	scheduledevents2.RegisterHandler("moderation_unmute", ScheduledUnmuteData{}, handleScheduledUnmute)
 	scheduledevents2.RegisterHandler("moderation_unban", ScheduledUnbanData{}, handleScheduledUnban)
+	scheduledevents2.RegisterHandler("moderation_unlock_channel", ScheduledUnlockData{}, handleScheduledUnlock)
 	scheduledevents2.RegisterLegacyMigrater("unmute", handleMigrateScheduledUnmute)
 	scheduledevents2.RegisterLegacyMigrater("mod_unban", handleMigrateScheduledUnban)
 
 	eventsystem.AddHandlerAsyncLast(bot.ConcurrentEventHandler(HandleGuildBanAddRemove), eventsystem.EventGuildBanAdd, eventsystem.EventGuildBanRemove)
 	eventsystem.AddHandlerAsyncLast(bot.ConcurrentEventHandler(HandleGuildMemberRemove), eventsystem.EventGuildMemberRemove)
+	eventsystem.AddHandlerAsyncLast(bot.ConcurrentEventHandler(HandleAppealReaction), eventsystem.EventMessageReactionAdd)
 	eventsystem.AddHandlerAsyncLast(LockMemberMuteMW(HandleMemberJoin), eventsystem.EventGuildMemberAdd)
 	eventsystem.AddHandlerAsyncLast(LockMemberMuteMW(HandleGuildMemberUpdate), eventsystem.EventGuildMemberUpdate)
 
@@ -218,6 +225,10 @@ func HandleGuildBanAddRemove(evt *eventsystem.EventData) {
 		if i > 0 {
 			// The bot was the one that performed the unban
 			common.RedisPool.Do(retryableredis.Cmd(nil, "DEL", RedisKeyUnbannedUser(guildID, user.ID)))
+			if i == unbanAlreadyLogged {
+				// UnbanUser takes care of the modlog and case
+				return
+			}
 			botPerformed = true
 		}
 
@@ -231,14 +242,10 @@ func HandleGuildBanAddRemove(evt *eventsystem.EventData) {
 		return
 	}
 
-	if config.IntActionChannel() == 0 {
-		return
-	}
-
 	var author *discordgo.User
 	reason := ""
 
-	if !botPerformed {
+	if !botPerformed && bot.BotProbablyHasPermission(guildID, 0, discordgo.PermissionViewAuditLogs) {
 		// If we poll it too fast then there sometimes wont be a audit log entry
 		time.Sleep(time.Second * 3)
 
@@ -254,17 +261,27 @@ func HandleGuildBanAddRemove(evt *eventsystem.EventData) {
 		}
 	}
 
-	if (action == MAUnbanned && !config.LogUnbans && !botPerformed) ||
-		(action == MABanned && !config.LogBans) {
-		return
-	}
-
 	// The bot only unbans people in the case of timed bans
 	if botPerformed {
 		author = common.BotUser
 		reason = "Timed ban expired"
 	}
 
+	caseAction := ActionBanned
+	if action == MAUnbanned {
+		caseAction = ActionUnbanned
+	}
+	logCase(guildID, caseAction, author, user, reason, 0, "")
+
+	if config.IntActionChannel() == 0 {
+		return
+	}
+
+	if (action == MAUnbanned && !config.LogUnbans && !botPerformed) ||
+		(action == MABanned && !config.LogBans) {
+		return
+	}
+
 	err = CreateModlogEmbed(config.IntActionChannel(), author, action, user, reason, "")
 	if err != nil {
 		logger.WithError(err).WithField("guild", guildID).Error("Failed sending " + action.Prefix + " log message")
@@ -280,7 +297,9 @@ func HandleGuildMemberRemove(evt *eventsystem.EventData) {
 		return
 	}
 
-	if config.IntActionChannel() == 0 {
+	// the audit log is checked even without a modlog channel so the kick still becomes a case,
+	// but there's no point in waiting for and fetching it if the bot can't view it
+	if !bot.BotProbablyHasPermission(data.GuildID, 0, discordgo.PermissionViewAuditLogs) {
 		return
 	}
 
@@ -297,6 +316,12 @@ func HandleGuildMemberRemove(evt *eventsystem.EventData) {
 		return
 	}
 
+	logCase(data.GuildID, ActionKicked, author, data.User, entry.Reason, 0, "")
+
+	if config.IntActionChannel() == 0 {
+		return
+	}
+
 	err = CreateModlogEmbed(config.IntActionChannel(), author, MAKick, data.User, entry.Reason, "")
 	if err != nil {
 		logger.WithError(err).WithField("guild", data.GuildID).Error("Failed sending kick log message")
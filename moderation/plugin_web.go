@@ -1,10 +1,15 @@
 package moderation
 
 import (
+	"encoding/csv"
 	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/jonas747/discordgo"
 	"github.com/jonas747/yagpdb/common"
 	"github.com/jonas747/yagpdb/web"
@@ -42,6 +47,23 @@ func (p *Plugin) InitWeb() {
 	subMux.Handle(pat.Post(""), postHandler)
 	subMux.Handle(pat.Post("/"), postHandler)
 	subMux.Handle(pat.Post("/clear_server_warnings"), clearServerWarnings)
+
+	notesHandler := web.ControllerHandler(HandleModerationNotes, "cp_moderation_notes")
+	subMux.Handle(pat.Get("/notes"), notesHandler)
+	subMux.Handle(pat.Post("/notes/:note/delete"), web.ControllerPostHandler(HandleDeleteNote, notesHandler, nil, "Deleted a moderator note"))
+
+	tempbansHandler := web.ControllerHandler(HandleModerationTempbans, "cp_moderation_tempbans")
+	subMux.Handle(pat.Get("/tempbans"), tempbansHandler)
+	subMux.Handle(pat.Post("/tempbans/:user/:action"), web.ControllerPostHandler(HandleUpdateTempban, tempbansHandler, TempbanUpdateForm{}, "Updated a temporary ban"))
+
+	subMux.Handle(pat.Get("/audit"), web.ControllerHandler(HandleModerationAudit, "cp_moderation_audit"))
+	subMux.Handle(pat.Get("/audit/json"), web.APIHandler(HandleModerationAuditJson))
+	subMux.Handle(pat.Get("/audit/csv"), http.HandlerFunc(HandleModerationAuditCSV))
+
+	getAppealHandler := web.ControllerHandler(HandleGetAppeal, "moderation_appeal_page")
+	postAppealHandler := web.ControllerPostHandler(HandlePostAppeal, getAppealHandler, AppealForm{}, "")
+	web.ServerPublicMux.Handle(pat.Get("/appeal"), getAppealHandler)
+	web.ServerPublicMux.Handle(pat.Post("/appeal"), web.RequireSessionMiddleware(postAppealHandler))
 }
 
 // The moderation page itself
@@ -69,10 +91,16 @@ func HandlePostModeration(w http.ResponseWriter, r *http.Request) (web.TemplateD
 
 	newConfig := ctx.Value(common.ContextKeyParsedForm).(*Config)
 	templateData["ModConfig"] = newConfig
+	templateData["DefaultDMMessage"] = DefaultDMMessage
 
-	err := newConfig.Save(activeGuild.ID)
+	thresholds, err := newConfig.WarnThresholds.Validate()
+	if err != nil {
+		templateData.AddAlerts(web.ErrorAlert(err.Error()))
+		return templateData, nil
+	}
+	newConfig.WarnThresholds = thresholds
 
-	templateData["DefaultDMMessage"] = DefaultDMMessage
+	err = newConfig.Save(activeGuild.ID)
 
 	return templateData, err
 }
@@ -90,6 +118,353 @@ func HandleClearServerWarnings(w http.ResponseWriter, r *http.Request) (web.Temp
 	return templateData, nil
 }
 
+const maxNotesShown = 100
+
+// canAccessNotes returns true if the logged in member can see the notes, same as noteCmdSecond it's only the NoteCmdRoles and people with manage server
+func canAccessNotes(r *http.Request, guildID int64) (bool, error) {
+	if web.HasPermissionCTX(r.Context(), discordgo.PermissionManageServer) {
+		return true, nil
+	}
+
+	member := web.ContextMember(r.Context())
+	if member == nil {
+		return false, nil
+	}
+
+	config, err := GetConfig(guildID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, v := range member.Roles {
+		if common.ContainsInt64Slice(config.NoteCmdRoles, v) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Lists the latest moderator notes, optionally only for a user
+func HandleModerationNotes(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	activeGuild, templateData := web.GetBaseCPContextData(r.Context())
+	templateData["VisibleURL"] = "/manage/" + discordgo.StrID(activeGuild.ID) + "/moderation/notes"
+
+	canAccess, err := canAccessNotes(r, activeGuild.ID)
+	if err != nil {
+		return templateData, err
+	}
+
+	if !canAccess {
+		return templateData.AddAlerts(web.ErrorAlert("Only members with manage server or one of the note roles can see the notes")), nil
+	}
+
+	q := common.GORM.Where("guild_id = ?", activeGuild.ID)
+
+	userID, _ := strconv.ParseInt(r.FormValue("user"), 10, 64)
+	if userID != 0 {
+		q = q.Where("user_id = ?", userID)
+		templateData["NotesUser"] = userID
+	}
+
+	var notes []*NoteModel
+	err = q.Order("id desc").Limit(maxNotesShown).Find(&notes).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return templateData, err
+	}
+
+	templateData["Notes"] = notes
+	return templateData, nil
+}
+
+func HandleDeleteNote(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	activeGuild, templateData := web.GetBaseCPContextData(r.Context())
+
+	canAccess, err := canAccessNotes(r, activeGuild.ID)
+	if err != nil {
+		return templateData, err
+	}
+
+	if !canAccess {
+		return templateData, web.NewPublicError("Only members with manage server or one of the note roles can delete notes")
+	}
+
+	noteID, _ := strconv.ParseInt(pat.Param(r, "note"), 10, 64)
+	err = common.GORM.Where("guild_id = ? AND id = ?", activeGuild.ID, noteID).Delete(NoteModel{}).Error
+	return templateData, err
+}
+
+// Lists the pending temporary bans
+func HandleModerationTempbans(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	activeGuild, templateData := web.GetBaseCPContextData(r.Context())
+	templateData["VisibleURL"] = "/manage/" + discordgo.StrID(activeGuild.ID) + "/moderation/tempbans"
+
+	tempbans, err := ListTempbans(r.Context(), activeGuild.ID)
+	if err != nil {
+		return templateData, err
+	}
+
+	templateData["Tempbans"] = tempbans
+	return templateData, nil
+}
+
+type TempbanUpdateForm struct {
+	Minutes int `valid:"0,5256000"`
+}
+
+func HandleUpdateTempban(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	activeGuild, templateData := web.GetBaseCPContextData(ctx)
+
+	form := ctx.Value(common.ContextKeyParsedForm).(*TempbanUpdateForm)
+	userID, _ := strconv.ParseInt(pat.Param(r, "user"), 10, 64)
+	action := pat.Param(r, "action")
+
+	if action == "cancel" {
+		cancelled, err := CancelTempban(ctx, activeGuild.ID, userID)
+		if err != nil {
+			return templateData, err
+		}
+
+		if !cancelled {
+			return templateData, web.NewPublicError("That user has no temporary ban")
+		}
+
+		logTempbanUpdate(activeGuild.ID, web.ContextUser(ctx), userID, nil)
+		return templateData, nil
+	}
+
+	current, err := tempbanExpiry(ctx, activeGuild.ID, userID)
+	if err != nil {
+		return templateData, err
+	}
+
+	if current == nil {
+		return templateData, web.NewPublicError("That user has no temporary ban")
+	}
+
+	duration := time.Duration(form.Minutes) * time.Minute
+
+	var newExpiry time.Time
+	switch action {
+	case "set":
+		newExpiry = time.Now().Add(duration)
+	case "extend":
+		newExpiry = current.Add(duration)
+	case "shorten":
+		newExpiry = current.Add(-duration)
+	default:
+		return templateData, web.NewPublicError("Unknown action")
+	}
+
+	updated, err := SetTempbanExpiry(ctx, activeGuild.ID, userID, newExpiry)
+	if err != nil {
+		return templateData, err
+	}
+
+	if !updated {
+		return templateData, web.NewPublicError("That user has no temporary ban")
+	}
+
+	logTempbanUpdate(activeGuild.ID, web.ContextUser(ctx), userID, &newExpiry)
+	return templateData, nil
+}
+
+const auditDateFormat = "2006-01-02"
+
+// auditFilterFromRequest parses the audit filters from the query, the range defaults to the last 30 days
+func auditFilterFromRequest(r *http.Request) (*AuditFilter, error) {
+	filter := &AuditFilter{
+		To:     time.Now().UTC().Truncate(time.Hour * 24).Add(time.Hour * 24),
+		Action: r.FormValue("action"),
+	}
+
+	if v := r.FormValue("to"); v != "" {
+		t, err := time.Parse(auditDateFormat, v)
+		if err != nil {
+			return nil, web.NewPublicError("Invalid to date, use YYYY-MM-DD")
+		}
+		// include the whole day
+		filter.To = t.Add(time.Hour * 24)
+	}
+
+	filter.From = filter.To.Add(-time.Hour * 24 * 30)
+	if v := r.FormValue("from"); v != "" {
+		t, err := time.Parse(auditDateFormat, v)
+		if err != nil {
+			return nil, web.NewPublicError("Invalid from date, use YYYY-MM-DD")
+		}
+		filter.From = t
+	}
+
+	filter.Author, _ = strconv.ParseInt(r.FormValue("mod"), 10, 64)
+	filter.Target, _ = strconv.ParseInt(r.FormValue("user"), 10, 64)
+
+	return filter, nil
+}
+
+// Lists the moderation cases in a date range, along with the number of actions per moderator
+func HandleModerationAudit(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	activeGuild, templateData := web.GetBaseCPContextData(r.Context())
+
+	filter, err := auditFilterFromRequest(r)
+	if err != nil {
+		return templateData, err
+	}
+
+	cases, err := RetrieveAuditCases(activeGuild.ID, filter)
+	if err != nil {
+		return templateData, err
+	}
+
+	stats, err := RetrieveModeratorStats(activeGuild.ID, filter)
+	if err != nil {
+		return templateData, err
+	}
+
+	templateData["AuditFilter"] = filter
+	templateData["AuditFrom"] = filter.From.Format(auditDateFormat)
+	templateData["AuditTo"] = filter.To.Add(-time.Hour * 24).Format(auditDateFormat)
+	templateData["AuditQuery"] = template.URL(r.URL.RawQuery)
+	templateData["AuditCases"] = cases
+	templateData["AuditModeratorStats"] = stats
+	templateData["AuditMaxEntries"] = MaxAuditEntries
+
+	return templateData, nil
+}
+
+func HandleModerationAuditJson(w http.ResponseWriter, r *http.Request) interface{} {
+	activeGuild, _ := web.GetBaseCPContextData(r.Context())
+
+	filter, err := auditFilterFromRequest(r)
+	if err != nil {
+		return err
+	}
+
+	cases, err := RetrieveAuditCases(activeGuild.ID, filter)
+	if err != nil {
+		return err
+	}
+
+	stats, err := RetrieveModeratorStats(activeGuild.ID, filter)
+	if err != nil {
+		return err
+	}
+
+	return map[string]interface{}{
+		"cases":      cases,
+		"moderators": stats,
+	}
+}
+
+func HandleModerationAuditCSV(w http.ResponseWriter, r *http.Request) {
+	activeGuild, _ := web.GetBaseCPContextData(r.Context())
+
+	filter, err := auditFilterFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cases, err := RetrieveAuditCases(activeGuild.ID, filter)
+	if err != nil {
+		web.CtxLogger(r.Context()).WithError(err).Error("failed retrieving moderation cases")
+		http.Error(w, "Failed retrieving cases", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="moderation-%d-%s-%s.csv"`,
+		activeGuild.ID, filter.From.Format(auditDateFormat), filter.To.Format(auditDateFormat)))
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"case", "time", "action", "user_id", "user", "moderator_id", "moderator", "reason", "duration_minutes", "logs"})
+	for _, c := range cases {
+		writer.Write([]string{
+			strconv.FormatInt(c.CaseNumber, 10),
+			c.CreatedAt.UTC().Format(time.RFC3339),
+			c.Action,
+			strconv.FormatInt(c.UserID, 10),
+			c.UserUsernameDiscrim,
+			strconv.FormatInt(c.AuthorID, 10),
+			c.AuthorUsernameDiscrim,
+			c.Reason,
+			strconv.Itoa(c.DurationMinutes),
+			c.LogsLink,
+		})
+	}
+
+	writer.Flush()
+	web.LogIgnoreErr(writer.Error())
+}
+
+type AppealForm struct {
+	Message string `valid:",10,2000,trimspace"`
+}
+
+// The public appeal page, users has to be logged in to see the form
+func HandleGetAppeal(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	g, templateData := web.GetBaseCPContextData(ctx)
+
+	config, err := GetConfig(g.ID)
+	if err != nil {
+		return templateData, err
+	}
+
+	if !config.AppealsEnabled || config.IntAppealsChannel() == 0 {
+		templateData.AddAlerts(web.ErrorAlert("Appeals are disabled on this server"))
+		return templateData, nil
+	}
+
+	user, ok := ctx.Value(common.ContextKeyUser).(*discordgo.User)
+	if !ok {
+		templateData["LoginURL"] = "/login?goto=" + url.QueryEscape(r.URL.Path)
+		return templateData, nil
+	}
+
+	kind, blocked, err := checkCanAppeal(g.ID, user.ID)
+	if err != nil {
+		return templateData, err
+	}
+
+	templateData["AppealKind"] = kind
+	templateData["AppealBlocked"] = blocked
+	return templateData, nil
+}
+
+func HandlePostAppeal(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
+	ctx := r.Context()
+	g, templateData := web.GetBaseCPContextData(ctx)
+
+	config, err := GetConfig(g.ID)
+	if err != nil {
+		return templateData, err
+	}
+
+	if !config.AppealsEnabled || config.IntAppealsChannel() == 0 {
+		return templateData, web.NewPublicError("Appeals are disabled on this server")
+	}
+
+	user := web.ContextUser(ctx)
+	kind, blocked, err := checkCanAppeal(g.ID, user.ID)
+	if err != nil {
+		return templateData, err
+	}
+
+	if blocked != "" {
+		return templateData, web.NewPublicError(blocked)
+	}
+
+	form := ctx.Value(common.ContextKeyParsedForm).(*AppealForm)
+	_, err = PostAppeal(config, g.ID, user, kind, form.Message)
+	if err == ErrAppealPending {
+		return templateData, web.NewPublicError("You already have a pending appeal, you will get a DM when it has been handled")
+	}
+
+	return templateData, err
+}
+
 var _ web.PluginWithServerHomeWidget = (*Plugin)(nil)
 
 func (p *Plugin) LoadServerHomeWidget(w http.ResponseWriter, r *http.Request) (web.TemplateData, error) {
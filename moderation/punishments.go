@@ -53,8 +53,9 @@ func getMemberWithFallback(gs *dstate.GuildState, user *discordgo.User) (ms *dst
 	return ms, false
 }
 
-// Kick or bans someone, uploading a hasebin log, and sending the report message in the action channel
-func punish(config *Config, p Punishment, guildID, channelID int64, author *discordgo.User, reason string, user *discordgo.User, duration time.Duration) error {
+// Kick or bans someone, uploading a hasebin log, and sending the report message in the action channel unless skipModlog is set.
+// A case is always created.
+func punish(config *Config, p Punishment, guildID, channelID int64, author *discordgo.User, reason string, user *discordgo.User, duration time.Duration, skipModlog bool) error {
 
 	config, err := getConfigIfNotSet(guildID, config)
 	if err != nil {
@@ -62,10 +63,12 @@ func punish(config *Config, p Punishment, guildID, channelID int64, author *disc
 	}
 
 	var action ModlogAction
+	caseAction := ActionKicked
 	if p == PunishmentKick {
 		action = MAKick
 	} else {
 		action = MABanned
+		caseAction = ActionBanned
 		if duration > 0 {
 			action.Footer = "Expires after: " + common.HumanizeDuration(common.DurationPrecisionMinutes, duration)
 		}
@@ -132,6 +135,12 @@ func punish(config *Config, p Punishment, guildID, channelID int64, author *disc
 		}
 	}
 
+	logCase(guildID, caseAction, author, user, reason, duration, logLink)
+
+	if skipModlog {
+		return nil
+	}
+
 	actionChannel := config.IntActionChannel()
 	err = CreateModlogEmbed(actionChannel, author, action, user, reason, logLink)
 	return err
@@ -160,16 +169,24 @@ func sendPunishDM(config *Config, dmMsg string, action ModlogAction, gs *dstate.
 		executed = "Failed executing template."
 	}
 
+	if config.AppealsEnabled && config.IntAppealsChannel() != 0 && (action.Prefix == MABanned.Prefix || action.Prefix == MAMute.Prefix) {
+		executed += "\nYou can appeal this at <" + AppealURL(gs.ID) + ">"
+	}
+
 	go bot.SendDM(member.ID, "**"+bot.GuildName(gs.ID)+":** "+executed)
 }
 
 func KickUser(config *Config, guildID, channelID int64, author *discordgo.User, reason string, user *discordgo.User) error {
+	return kickUser(config, guildID, channelID, author, reason, user, false)
+}
+
+func kickUser(config *Config, guildID, channelID int64, author *discordgo.User, reason string, user *discordgo.User, skipModlog bool) error {
 	config, err := getConfigIfNotSet(guildID, config)
 	if err != nil {
 		return common.ErrWithCaller(err)
 	}
 
-	err = punish(config, PunishmentKick, guildID, channelID, author, reason, user, 0)
+	err = punish(config, PunishmentKick, guildID, channelID, author, reason, user, 0, skipModlog)
 	if err != nil {
 		return err
 	}
@@ -224,9 +241,13 @@ func DeleteMessages(channelID int64, filterUser int64, deleteNum, fetchNum int)
 }
 
 func BanUserWithDuration(config *Config, guildID, channelID int64, author *discordgo.User, reason string, user *discordgo.User, duration time.Duration) error {
+	return banUserWithDuration(config, guildID, channelID, author, reason, user, duration, false)
+}
+
+func banUserWithDuration(config *Config, guildID, channelID int64, author *discordgo.User, reason string, user *discordgo.User, duration time.Duration, skipModlog bool) error {
 	// Set a key in redis that marks that this user has appeared in the modlog already
 	common.RedisPool.Do(retryableredis.Cmd(nil, "SETEX", RedisKeyBannedUser(guildID, user.ID), "60", "1"))
-	err := punish(config, PunishmentBan, guildID, channelID, author, reason, user, duration)
+	err := punish(config, PunishmentBan, guildID, channelID, author, reason, user, duration, skipModlog)
 	if err != nil {
 		return err
 	}
@@ -250,6 +271,30 @@ func BanUser(config *Config, guildID, channelID int64, author *discordgo.User, r
 	return BanUserWithDuration(config, guildID, channelID, author, reason, user, 0)
 }
 
+// UnbanUser lifts a ban and cancels any scheduled unban, it's logged in the modlog regardless of the LogUnbans setting
+func UnbanUser(config *Config, guildID int64, author *discordgo.User, reason string, user *discordgo.User) error {
+	config, err := getConfigIfNotSet(guildID, config)
+	if err != nil {
+		return common.ErrWithCaller(err)
+	}
+
+	// Mark it as already logged so the ban remove handler dosen't make a duplicate entry
+	common.RedisPool.Do(retryableredis.FlatCmd(nil, "SETEX", RedisKeyUnbannedUser(guildID, user.ID), 30, unbanAlreadyLogged))
+
+	err = common.BotSession.GuildBanDelete(guildID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = seventsmodels.ScheduledEvents(qm.Where("event_name='moderation_unban' AND  guild_id = ? AND (data->>'user_id')::bigint = ?", guildID, user.ID)).DeleteAll(context.Background(), common.PQ)
+	common.LogIgnoreError(err, "[moderation] failed clearing unban events", nil)
+
+	logger.Infof("MODERATION: %s %s %s cause %q", author.Username, MAUnbanned.Prefix, user.Username, reason)
+
+	logCase(guildID, ActionUnbanned, author, user, reason, 0, "")
+	return CreateModlogEmbed(config.IntActionChannel(), author, MAUnbanned, user, reason, "")
+}
+
 var (
 	ErrNoMuteRole = errors.New("No mute role")
 )
@@ -356,12 +401,18 @@ func MuteUnmuteUser(config *Config, mute bool, guildID, channelID int64, author
 
 	dmMsg := config.UnmuteMessage
 	action := MAUnmute
+	caseAction := ActionUnMuted
+	caseDuration := time.Duration(0)
 	if mute {
 		action = MAMute
 		action.Footer = "Expires after: " + strconv.Itoa(duration) + " minutes"
 		dmMsg = config.MuteMessage
+		caseAction = ActionMuted
+		caseDuration = time.Duration(duration) * time.Minute
 	}
 
+	logCase(guildID, caseAction, author, member.DGoUser(), reason, caseDuration, logLink)
+
 	gs := bot.State.Guild(true, guildID)
 	if gs != nil {
 		sendPunishDM(config, dmMsg, action, gs, author, member, time.Duration(duration)*time.Minute, reason)
@@ -426,6 +477,11 @@ func RemoveMemberMuteRole(config *Config, id int64, currentRoles []int64, mute M
 }
 
 func WarnUser(config *Config, guildID, channelID int64, author *discordgo.User, target *discordgo.User, message string) error {
+	return WarnUserWithExpiry(config, guildID, channelID, author, target, message, 0)
+}
+
+// WarnUserWithExpiry warns the user, the warning expires after the duration or never if it's 0
+func WarnUserWithExpiry(config *Config, guildID, channelID int64, author *discordgo.User, target *discordgo.User, message string, expiry time.Duration) error {
 	warning := &WarningModel{
 		GuildID:               guildID,
 		UserID:                discordgo.StrID(target.ID),
@@ -444,12 +500,19 @@ func WarnUser(config *Config, guildID, channelID int64, author *discordgo.User,
 		warning.LogsLink = CreateLogs(guildID, channelID, author)
 	}
 
+	if expiry > 0 {
+		expiresAt := time.Now().Add(expiry)
+		warning.ExpiresAt = &expiresAt
+	}
+
 	// Create the entry in the database
 	err = common.GORM.Create(warning).Error
 	if err != nil {
 		return common.ErrWithCaller(err)
 	}
 
+	logCase(guildID, ActionWarned, author, target, message, 0, warning.LogsLink)
+
 	gs := bot.State.Guild(true, guildID)
 	ms, _ := bot.GetMember(guildID, target.ID)
 	if gs != nil && ms != nil {
@@ -458,12 +521,20 @@ func WarnUser(config *Config, guildID, channelID int64, author *discordgo.User,
 
 	// go bot.SendDM(target.ID, fmt.Sprintf("**%s**: You have been warned for: %s", bot.GuildName(guildID), message))
 
+	// the warning is already saved, so the thresholds are applied even if the modlog entry fails
+	var modlogErr error
 	if config.WarnSendToModlog && config.ActionChannel != "" {
 		parsedActionChannel, _ := strconv.ParseInt(config.ActionChannel, 10, 64)
-		err = CreateModlogEmbed(parsedActionChannel, author, MAWarned, target, message, warning.LogsLink)
-		if err != nil {
-			return common.ErrWithCaller(err)
-		}
+		modlogErr = CreateModlogEmbed(parsedActionChannel, author, MAWarned, target, message, warning.LogsLink)
+	}
+
+	err = applyWarnThresholds(config, guildID, channelID, target)
+	if err != nil {
+		logger.WithError(err).WithField("guild", guildID).Error("failed applying warning threshold punishment")
+	}
+
+	if modlogErr != nil {
+		return common.ErrWithCaller(modlogErr)
 	}
 
 	return nil
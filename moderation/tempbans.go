@@ -0,0 +1,297 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jonas747/dcmd"
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/commands"
+	"github.com/jonas747/yagpdb/common"
+	seventsmodels "github.com/jonas747/yagpdb/common/scheduledevents2/models"
+	"github.com/volatiletech/sqlboiler/queries/qm"
+)
+
+// Tempban is a ban with a pending moderation_unban event
+type Tempban struct {
+	UserID    int64
+	ExpiresAt time.Time
+
+	// From the latest ban case of the user, if there is one
+	UserUsernameDiscrim string
+	Reason              string
+}
+
+func (t *Tempban) Remaining() time.Duration {
+	return time.Until(t.ExpiresAt)
+}
+
+func (t *Tempban) HumanRemaining() string {
+	remaining := t.Remaining()
+	if remaining < time.Minute {
+		return "less than a minute"
+	}
+
+	return common.HumanizeDuration(common.DurationPrecisionMinutes, remaining)
+}
+
+const tempbanEventQuery = "event_name='moderation_unban' AND guild_id = ? AND processed = false"
+
+// ListTempbans returns the pending temporary bans of a server, soonest to expire first
+func ListTempbans(ctx context.Context, guildID int64) ([]*Tempban, error) {
+	events, err := seventsmodels.ScheduledEvents(qm.Where(tempbanEventQuery, guildID), qm.OrderBy("triggers_at asc")).AllG(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Tempban, 0, len(events))
+	userIDs := make([]int64, 0, len(events))
+	for _, v := range events {
+		var data ScheduledUnbanData
+		err = json.Unmarshal(v.Data, &data)
+		if err != nil {
+			logger.WithError(err).WithField("guild", guildID).Error("failed decoding unban event data")
+			continue
+		}
+
+		result = append(result, &Tempban{
+			UserID:    data.UserID,
+			ExpiresAt: v.TriggersAt,
+		})
+		userIDs = append(userIDs, data.UserID)
+	}
+
+	if len(userIDs) < 1 {
+		return result, nil
+	}
+
+	var cases []*CaseModel
+	err = common.GORM.Where("guild_id = ? AND action = ? AND user_id IN (?)", guildID, ActionBanned, userIDs).Order("case_number asc").Find(&cases).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// ordered by case number so the latest case ends up being used
+	for _, c := range cases {
+		for _, tb := range result {
+			if tb.UserID == c.UserID {
+				tb.UserUsernameDiscrim = c.UserUsernameDiscrim
+				tb.Reason = c.Reason
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// SetTempbanExpiry changes when the user is unbanned, returns false if the user has no pending unban
+func SetTempbanExpiry(ctx context.Context, guildID, userID int64, t time.Time) (bool, error) {
+	rows, err := seventsmodels.ScheduledEvents(qm.Where(tempbanEventQuery+" AND (data->>'user_id')::bigint = ?", guildID, userID)).
+		UpdateAllG(ctx, seventsmodels.M{"triggers_at": t})
+	return rows > 0, err
+}
+
+// CancelTempban removes the pending unban, making the ban permanent. Returns false if the user has no pending unban
+func CancelTempban(ctx context.Context, guildID, userID int64) (bool, error) {
+	rows, err := seventsmodels.ScheduledEvents(qm.Where(tempbanEventQuery+" AND (data->>'user_id')::bigint = ?", guildID, userID)).DeleteAll(ctx, common.PQ)
+	return rows > 0, err
+}
+
+// logTempbanUpdate creates a case and modlog entry for a changed tempban, newExpiry is nil if it was cancelled
+func logTempbanUpdate(guildID int64, author *discordgo.User, userID int64, newExpiry *time.Time) {
+	target, err := common.BotSession.User(userID)
+	if err != nil {
+		target = &discordgo.User{
+			ID:            userID,
+			Username:      "unknown",
+			Discriminator: "????",
+		}
+	}
+
+	caseAction := ActionTempbanCancelled
+	modlogAction := MATempbanCancelled
+	reason := "The ban is now permanent"
+	duration := time.Duration(0)
+	if newExpiry != nil {
+		caseAction = ActionTempbanUpdated
+		modlogAction = MATempbanUpdated
+		reason = "The ban will be lifted shortly"
+
+		if remaining := time.Until(*newExpiry); remaining > 0 {
+			duration = remaining
+			reason = "The ban now expires after " + common.HumanizeDuration(common.DurationPrecisionMinutes, remaining)
+		}
+	}
+
+	logCase(guildID, caseAction, author, target, reason, duration, "")
+
+	config, err := GetConfig(guildID)
+	if err != nil {
+		logger.WithError(err).WithField("guild", guildID).Error("Failed retrieving config")
+		return
+	}
+
+	err = CreateModlogEmbed(config.IntActionChannel(), author, modlogAction, target, reason, "")
+	if err != nil {
+		logger.WithError(err).WithField("guild", guildID).Error("Failed sending tempban update log message")
+	}
+}
+
+// tempbanExpiry returns the current expiry of the users tempban, or nil if there's none
+func tempbanExpiry(ctx context.Context, guildID, userID int64) (*time.Time, error) {
+	tempbans, err := ListTempbans(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range tempbans {
+		if v.UserID == userID {
+			return &v.ExpiresAt, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// banCmdSecond runs the same checks as the ban command
+func banCmdSecond(parsed *dcmd.Data) error {
+	config, _, err := MBaseCmd(parsed, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = MBaseCmdSecond(parsed, "", true, discordgo.PermissionBanMembers, config.BanCmdRoles, config.BanEnabled)
+	return err
+}
+
+var TempbanCommands = []*commands.YAGCommand{
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "Tempbans",
+		Description:   "Lists the temporary bans and when they expire",
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			err := banCmdSecond(parsed)
+			if err != nil {
+				return nil, err
+			}
+
+			tempbans, err := ListTempbans(parsed.Context(), parsed.GS.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(tempbans) < 1 {
+				return "No temporary bans", nil
+			}
+
+			var out strings.Builder
+			for i, v := range tempbans {
+				if i >= 25 {
+					fmt.Fprintf(&out, "...and %d more, see the control panel for the full list", len(tempbans)-i)
+					break
+				}
+
+				name := v.UserUsernameDiscrim
+				if name == "" {
+					name = "unknown user"
+				}
+
+				fmt.Fprintf(&out, "`%d` %s - expires in %s\n", v.UserID, name, v.HumanRemaining())
+			}
+
+			return common.EscapeSpecialMentions(out.String()), nil
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled:   true,
+		CmdCategory:     commands.CategoryModeration,
+		Name:            "EditTempban",
+		Description:     "Changes when a temporary ban expires",
+		LongDescription: "By default the ban will expire after the specified duration from now, use -extend or -shorten to add or remove time from the current expiry instead.",
+		RequiredArgs:    2,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
+			&dcmd.ArgDef{Name: "Duration", Type: &commands.DurationArg{}},
+		},
+		ArgSwitches: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Switch: "extend", Name: "Add to the current expiry"},
+			&dcmd.ArgDef{Switch: "shorten", Name: "Remove from the current expiry"},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			err := banCmdSecond(parsed)
+			if err != nil {
+				return nil, err
+			}
+
+			userID := parsed.Args[0].Int64()
+			duration := parsed.Args[1].Value.(time.Duration)
+
+			current, err := tempbanExpiry(parsed.Context(), parsed.GS.ID, userID)
+			if err != nil {
+				return nil, err
+			}
+
+			if current == nil {
+				return "That user has no temporary ban", nil
+			}
+
+			newExpiry := time.Now().Add(duration)
+			if parsed.Switches["extend"].Value != nil && parsed.Switches["extend"].Value.(bool) {
+				newExpiry = current.Add(duration)
+			} else if parsed.Switches["shorten"].Value != nil && parsed.Switches["shorten"].Value.(bool) {
+				newExpiry = current.Add(-duration)
+			}
+
+			updated, err := SetTempbanExpiry(parsed.Context(), parsed.GS.ID, userID, newExpiry)
+			if err != nil {
+				return nil, err
+			}
+
+			if !updated {
+				return "That user has no temporary ban", nil
+			}
+
+			logTempbanUpdate(parsed.GS.ID, parsed.Msg.Author, userID, &newExpiry)
+
+			if time.Until(newExpiry) <= 0 {
+				return "👌 The ban will be lifted shortly", nil
+			}
+
+			return "👌 The ban now expires in " + common.HumanizeDuration(common.DurationPrecisionMinutes, time.Until(newExpiry)), nil
+		},
+	},
+	&commands.YAGCommand{
+		CustomEnabled: true,
+		CmdCategory:   commands.CategoryModeration,
+		Name:          "CancelTempban",
+		Description:   "Cancels the scheduled unban of a temporary ban, making it permanent",
+		RequiredArgs:  1,
+		Arguments: []*dcmd.ArgDef{
+			&dcmd.ArgDef{Name: "User", Type: dcmd.UserID},
+		},
+		RunFunc: func(parsed *dcmd.Data) (interface{}, error) {
+			err := banCmdSecond(parsed)
+			if err != nil {
+				return nil, err
+			}
+
+			userID := parsed.Args[0].Int64()
+			cancelled, err := CancelTempban(parsed.Context(), parsed.GS.ID, userID)
+			if err != nil {
+				return nil, err
+			}
+
+			if !cancelled {
+				return "That user has no temporary ban", nil
+			}
+
+			logTempbanUpdate(parsed.GS.ID, parsed.Msg.Author, userID, nil)
+
+			return "👌 The ban is now permanent", nil
+		},
+	},
+}
@@ -0,0 +1,145 @@
+package moderation
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jonas747/discordgo"
+	"github.com/jonas747/yagpdb/bot"
+	"github.com/jonas747/yagpdb/common"
+)
+
+const (
+	WarnThresholdActionMute = "mute"
+	WarnThresholdActionKick = "kick"
+	WarnThresholdActionBan  = "ban"
+
+	MaxWarnThresholds = 10
+)
+
+// WarnThreshold is a punishment automatically applied when a user reaches a number of warnings
+type WarnThreshold struct {
+	Warnings int
+	Action   string
+	// Duration in minutes for mutes and bans, 0 is a permanent ban
+	Duration int
+}
+
+// WarnThresholdList is stored as json in the moderation config
+type WarnThresholdList []WarnThreshold
+
+func (w WarnThresholdList) Value() (driver.Value, error) {
+	if w == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal(w)
+}
+
+func (w *WarnThresholdList) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*w = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, w)
+	case string:
+		return json.Unmarshal([]byte(v), w)
+	}
+
+	return errors.New("unsupported type for WarnThresholdList")
+}
+
+// Validate removes empty thresholds (0 warnings), sorts them and checks the actions and durations
+func (w WarnThresholdList) Validate() (WarnThresholdList, error) {
+	result := make(WarnThresholdList, 0, len(w))
+	for _, v := range w {
+		if v.Warnings < 1 {
+			continue
+		}
+
+		if v.Warnings > 1000 {
+			return nil, errors.New("Number of warnings can't be above 1000")
+		}
+
+		switch v.Action {
+		case WarnThresholdActionMute:
+			if v.Duration < 1 || v.Duration > 10080 {
+				return nil, errors.New("Mute duration has to be between 1 and 10080 minutes")
+			}
+		case WarnThresholdActionBan:
+			if v.Duration < 0 || v.Duration > 525600 {
+				return nil, errors.New("Ban duration has to be between 0 (permanent) and 525600 minutes")
+			}
+		case WarnThresholdActionKick:
+			v.Duration = 0
+		default:
+			return nil, fmt.Errorf("Unknown warning threshold action %q", v.Action)
+		}
+
+		for _, existing := range result {
+			if existing.Warnings == v.Warnings {
+				return nil, fmt.Errorf("More than one threshold at %d warnings", v.Warnings)
+			}
+		}
+
+		result = append(result, v)
+	}
+
+	if len(result) > MaxWarnThresholds {
+		return nil, fmt.Errorf("Max %d warning thresholds", MaxWarnThresholds)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Warnings < result[j].Warnings
+	})
+
+	return result, nil
+}
+
+// applyWarnThresholds punishes the user if the number of warnings they now have matches one of the configured thresholds
+func applyWarnThresholds(config *Config, guildID, channelID int64, target *discordgo.User) error {
+	if len(config.WarnThresholds) < 1 {
+		return nil
+	}
+
+	var count int
+	err := common.GORM.Model(&WarningModel{}).Where("guild_id = ? AND user_id = ? AND "+WarningNotExpiredQuery, guildID, discordgo.StrID(target.ID)).Count(&count).Error
+	if err != nil {
+		return err
+	}
+
+	var threshold *WarnThreshold
+	for i, v := range config.WarnThresholds {
+		if v.Warnings == count {
+			threshold = &config.WarnThresholds[i]
+			break
+		}
+	}
+
+	if threshold == nil {
+		return nil
+	}
+
+	reason := fmt.Sprintf("Reached %d warnings", count)
+
+	switch threshold.Action {
+	case WarnThresholdActionMute:
+		member, err := bot.GetMember(guildID, target.ID)
+		if err != nil || member == nil {
+			return err
+		}
+
+		return MuteUnmuteUser(config, true, guildID, channelID, common.BotUser, reason, member, threshold.Duration)
+	case WarnThresholdActionKick:
+		return KickUser(config, guildID, channelID, common.BotUser, reason, target)
+	case WarnThresholdActionBan:
+		return BanUserWithDuration(config, guildID, channelID, common.BotUser, reason, target, time.Duration(threshold.Duration)*time.Minute)
+	}
+
+	return nil
+}